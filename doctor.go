@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sanjaix21/krakeneye/internal/display"
+	"sanjaix21/krakeneye/internal/parser"
+	"sanjaix21/krakeneye/internal/sites"
+	"time"
+)
+
+// runDoctor checks every parser against its saved pages and, unless --offline, against
+// the fastest working mirror of every site. It returns the exit code.
+func runDoctor(args []string) int {
+	doctorFlags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	offline := doctorFlags.Bool("offline", false, "only check the built-in parsers against their saved pages")
+	query := doctorFlags.String("query", parser.DefaultHealthQuery, "search used to check live mirrors")
+	timeout := doctorFlags.Duration("timeout", time.Minute, "time allowed to check one site")
+	doctorFlags.Parse(args)
+
+	healthy := true
+
+	fmt.Println("🧪 Checking parsers against saved pages...")
+	for _, report := range parser.CheckFixtures() {
+		display.PrintHealthReport(report)
+		healthy = healthy && report.Healthy
+	}
+
+	if !*offline {
+		fmt.Println("🌍 Checking parsers against live mirrors...")
+		for _, site := range sites.AllSites() {
+			result, err := site.FastestMirror()
+			if err != nil {
+				fmt.Printf("🩺 %s: ❌ %v\n", site.Name, err)
+				healthy = false
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			report := parser.CheckLive(ctx, result.SiteName, result.Mirror, *query)
+			cancel()
+
+			display.PrintHealthReport(report)
+			healthy = healthy && report.Healthy
+		}
+	}
+
+	if !healthy {
+		fmt.Println("💔 Some parsers need attention, see the failed checks above")
+		return 1
+	}
+
+	fmt.Println("💚 All parsers look healthy")
+	return 0
+}
@@ -2,7 +2,10 @@ module sanjaix21/krakeneye
 
 go 1.24.2
 
-require github.com/PuerkitoBio/goquery v1.10.3
+require (
+	github.com/PuerkitoBio/goquery v1.10.3
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/andybalholm/cascadia v1.3.3 // indirect
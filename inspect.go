@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sanjaix21/krakeneye/internal/display"
+	"sanjaix21/krakeneye/internal/metainfo"
+	"strings"
+	"time"
+)
+
+// runInspect prints what a .torrent file on disk or behind a URL holds. It returns the exit code.
+func runInspect(args []string) int {
+	inspectFlags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	timeout := inspectFlags.Duration("timeout", 30*time.Second, "time allowed to download the .torrent")
+	inspectFlags.Parse(args)
+
+	if inspectFlags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: krakeneye inspect [--timeout 30s] <file.torrent|url>")
+		return 2
+	}
+	source := inspectFlags.Arg(0)
+
+	var torrent *metainfo.Torrent
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		torrent, _, err = metainfo.Fetch(ctx, source)
+		cancel()
+	} else {
+		torrent, err = metainfo.Load(source)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	display.PrintTorrentMeta(torrent)
+	return 0
+}
@@ -0,0 +1,237 @@
+// Package bencode reads and writes the encoding .torrent files use. Values map to Go as
+// integers to int64, byte strings to string, lists to []any and dictionaries to map[string]any.
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("invalid bencode")
+
+// a crafted file must not make us recurse forever
+const maxDepth = 64
+
+// Decode reads the one value data holds, trailing bytes are an error
+func Decode(data []byte) (any, error) {
+	d := decoder{data: data}
+	value, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, d.errorf("trailing data")
+	}
+	return value, nil
+}
+
+// RawValue returns the encoded bytes of key in the top level dictionary exactly as they
+// appear in data. The info-hash is taken over these, re-encoding could change them.
+func RawValue(data []byte, key string) ([]byte, error) {
+	d := decoder{data: data}
+	if d.peek() != 'd' {
+		return nil, d.errorf("not a dictionary")
+	}
+	d.pos++
+
+	for d.peek() != 'e' {
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+
+		start := d.pos
+		if _, err := d.value(1); err != nil {
+			return nil, err
+		}
+		if name == key {
+			return data[start:d.pos], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no %q key", ErrSyntax, key)
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at byte %d: %s", ErrSyntax, d.pos, fmt.Sprintf(format, args...))
+}
+
+// peek is the next byte, 0 at the end of data
+func (d *decoder) peek() byte {
+	if d.pos >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos]
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, d.errorf("nested too deep")
+	}
+
+	switch c := d.peek(); {
+	case c == 'i':
+		return d.integer()
+
+	case c >= '0' && c <= '9':
+		return d.string()
+
+	case c == 'l':
+		d.pos++
+		list := []any{}
+		for d.peek() != 'e' {
+			if d.peek() == 0 {
+				return nil, d.errorf("unterminated list")
+			}
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		d.pos++
+		return list, nil
+
+	case c == 'd':
+		d.pos++
+		dict := map[string]any{}
+		for d.peek() != 'e' {
+			if d.peek() == 0 {
+				return nil, d.errorf("unterminated dictionary")
+			}
+			key, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = item
+		}
+		d.pos++
+		return dict, nil
+
+	case c == 0:
+		return nil, d.errorf("unexpected end")
+
+	default:
+		return nil, d.errorf("unexpected %q", c)
+	}
+}
+
+// integer reads i<digits>e
+func (d *decoder) integer() (int64, error) {
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, d.errorf("unterminated integer")
+	}
+
+	digits := string(d.data[d.pos+1 : d.pos+end])
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || strings.HasPrefix(digits, "+") || (len(digits) > 1 && (digits[0] == '0' || digits[:2] == "-0")) {
+		return 0, d.errorf("bad integer %q", digits)
+	}
+
+	d.pos += end + 1
+	return n, nil
+}
+
+// string reads <length>:<bytes>
+func (d *decoder) string() (string, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", d.errorf("unterminated string length")
+	}
+
+	// Atoi would take "+5" and "-0", a length is plain digits
+	digits := d.data[d.pos : d.pos+colon]
+	if len(digits) == 0 || bytes.ContainsFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) {
+		return "", d.errorf("bad string length %q", digits)
+	}
+	length, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return "", d.errorf("bad string length %q", digits)
+	}
+
+	start := d.pos + colon + 1
+	if length > len(d.data)-start {
+		return "", d.errorf("string runs past the end")
+	}
+
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+// Encode writes v, dictionary keys come out sorted as the format requires. Any Go int type
+// and []byte are accepted next to the types Decode returns.
+func Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case int64:
+		fmt.Fprintf(buf, "i%de", v)
+	case int:
+		fmt.Fprintf(buf, "i%de", v)
+	case int32:
+		fmt.Fprintf(buf, "i%de", v)
+	case bool:
+		// some writers store flags like private as 0/1
+		if v {
+			buf.WriteString("i1e")
+		} else {
+			buf.WriteString("i0e")
+		}
+	case string:
+		fmt.Fprintf(buf, "%d:%s", len(v), v)
+	case []byte:
+		fmt.Fprintf(buf, "%d:", len(v))
+		buf.Write(v)
+	case []any:
+		buf.WriteByte('l')
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case []string:
+		buf.WriteByte('l')
+		for _, item := range v {
+			fmt.Fprintf(buf, "%d:%s", len(item), item)
+		}
+		buf.WriteByte('e')
+	case map[string]any:
+		buf.WriteByte('d')
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%d:%s", len(key), key)
+			if err := encode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("bencode: can't encode %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		encoded string
+		value   any
+	}{
+		{"i0e", int64(0)},
+		{"i42e", int64(42)},
+		{"i-7e", int64(-7)},
+		{"i9223372036854775807e", int64(9223372036854775807)},
+		{"0:", ""},
+		{"4:spam", "spam"},
+		{"3:a:b", "a:b"},
+		{"le", []any{}},
+		{"l4:spami3ee", []any{"spam", int64(3)}},
+		{"de", map[string]any{}},
+		{"d3:bar4:spam3:fooi42ee", map[string]any{"bar": "spam", "foo": int64(42)}},
+		{"d4:infod6:lengthi10e4:name1:aee", map[string]any{"info": map[string]any{"length": int64(10), "name": "a"}}},
+		{"lli1eelee", []any{[]any{int64(1)}, []any{}}},
+	}
+
+	for _, tt := range tests {
+		decoded, err := Decode([]byte(tt.encoded))
+		if err != nil {
+			t.Errorf("Decode(%q): %v", tt.encoded, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, tt.value) {
+			t.Errorf("Decode(%q) = %#v, want %#v", tt.encoded, decoded, tt.value)
+		}
+
+		encoded, err := Encode(decoded)
+		if err != nil {
+			t.Errorf("Encode(%#v): %v", decoded, err)
+			continue
+		}
+		if string(encoded) != tt.encoded {
+			t.Errorf("Encode(Decode(%q)) = %q", tt.encoded, encoded)
+		}
+	}
+}
+
+func TestEncodeSortsKeysAndGoTypes(t *testing.T) {
+	encoded, err := Encode(map[string]any{
+		"zeta":    []byte("raw"),
+		"alpha":   true,
+		"private": false,
+		"n":       32,
+		"list":    []string{"a", "bc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "d5:alphai1e4:listl1:a2:bce1:ni32e7:privatei0e4:zeta3:rawe"
+	if string(encoded) != want {
+		t.Errorf("got %q, want %q", encoded, want)
+	}
+
+	if _, err := Encode(3.14); err == nil {
+		t.Error("Encode accepted a float")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"x",
+		// integers
+		"i",
+		"i42",
+		"ie",
+		"i-e",
+		"i03e",
+		"i-0e",
+		"i+5e",
+		"i1.5e",
+		"i99999999999999999999e",
+		// strings
+		"5",
+		"5:abc",
+		"4:spam trailing",
+		"-1:a",
+		"+5:hello",
+		" 5:hello",
+		":a",
+		"99999999999999999999999:a",
+		// lists and dictionaries
+		"l",
+		"li1e",
+		"d",
+		"d3:foo",
+		"d3:fooe",
+		"di1ei2ee",
+		"e",
+	}
+
+	for _, input := range tests {
+		if value, err := Decode([]byte(input)); err == nil {
+			t.Errorf("Decode(%q) = %#v, want an error", input, value)
+		} else if !errors.Is(err, ErrSyntax) {
+			t.Errorf("Decode(%q) error %v is not ErrSyntax", input, err)
+		}
+	}
+}
+
+func TestDecodeDepthLimit(t *testing.T) {
+	nested := func(depth int) []byte {
+		return []byte(strings.Repeat("l", depth) + strings.Repeat("e", depth))
+	}
+
+	if _, err := Decode(nested(maxDepth)); err != nil {
+		t.Errorf("%d nested lists: %v", maxDepth, err)
+	}
+	if _, err := Decode(nested(maxDepth + 2)); err == nil {
+		t.Errorf("%d nested lists decoded, want the depth limit", maxDepth+2)
+	}
+	if _, err := Decode(nested(100000)); err == nil {
+		t.Error("100000 nested lists decoded")
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	data := []byte("d8:announce3:url4:infod6:lengthi10e4:name1:ae7:comment2:hie")
+
+	raw, err := RawValue(data, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "d6:lengthi10e4:name1:ae" {
+		t.Errorf("RawValue = %q", raw)
+	}
+
+	if _, err := RawValue(data, "missing"); err == nil {
+		t.Error("RawValue found a missing key")
+	}
+	if _, err := RawValue([]byte("l4:infoe"), "info"); err == nil {
+		t.Error("RawValue read a list as a dictionary")
+	}
+}
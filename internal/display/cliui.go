@@ -2,9 +2,13 @@ package display
 
 import (
 	"fmt"
+	"sanjaix21/krakeneye/internal/metainfo"
 	"sanjaix21/krakeneye/internal/parser"
 	"sanjaix21/krakeneye/internal/ranker"
+	"sanjaix21/krakeneye/internal/sites"
 	"sort"
+	"strings"
+	"time"
 )
 
 type DebugDisplay struct {
@@ -34,20 +38,36 @@ func (dd *DebugDisplay) PrintTorrentDebug(torrent parser.TorrentFile, index int)
 	fmt.Printf("🔗 Link       : %s\n", torrent.Href)
 	fmt.Printf("📁 Size       : %s (%.2f GB)\n", torrent.SizeRaw, torrent.Size)
 	fmt.Printf("🧲 Magnet     : %s\n", torrent.MagnetLink)
+	fmt.Printf("#️⃣ InfoHash   : %s\n", torrent.InfoHash)
 	fmt.Printf("📊 Category   : %s\n", torrent.Category)
 	fmt.Printf("📅 Uploaded   : %s\n", torrent.UploadDate)
 	fmt.Printf("🚀 Seeders    : %d\n", torrent.Seeders)
+	fmt.Printf("🧭 Sites      : %s\n", sitesLabel(torrent))
+	fmt.Printf("🛰️ Mirror     : %s\n", torrent.Mirror)
 	fmt.Printf("🩸 Leechers   : %d\n", torrent.Leechers)
 	fmt.Printf("📤 Uploader   : %s (Trusted: %t)\n", torrent.Uploader, torrent.Trusted)
 	fmt.Printf("🌐 Language   : %s\n", torrent.Language)
 	fmt.Printf("⏬ Downloads  : %d\n", torrent.Downloads)
 	fmt.Printf("🎞️ Source     : %s\n", torrent.Source)
 	fmt.Printf("🖥️ Resolution : %s\n", torrent.Resolution)
-	fmt.Printf("🎧 Audio      : %s\n", torrent.AudioCodec)
+	fmt.Printf("🎧 Audio      : %s %s (%d tracks: %s)\n", torrent.AudioCodec, torrent.AudioChannels, torrent.AudioTracks, strings.Join(torrent.AudioLanguages, ", "))
 	fmt.Printf("📼 Video      : %s\n", torrent.VideoCodec)
 	fmt.Printf("📦 Container  : %s\n", torrent.Container)
 	fmt.Printf("🌈 Bit Depth  : %s\n", torrent.BitDepth)
+	fmt.Printf("🔆 HDR        : %s\n", hdrLabel(torrent.HDRFormat))
+	if report := torrent.MediaInfo; report != nil {
+		v := report.Video
+		fmt.Printf("🔬 MediaInfo  : %dx%d %s %s, %.3f fps, %d kb/s, %s\n", v.Width, v.Height, v.Format, v.Profile, v.FrameRate, v.Bitrate, report.Runtime)
+		fmt.Printf("💬 Subtitles  : %s\n", strings.Join(report.SubtitleLanguages(), ", "))
+	}
 	fmt.Printf("📃 MetaInfo   : %s\n", torrent.MetaInfo)
+	fmt.Printf("🗂️ Files      : %d\n", len(torrent.Files))
+	for _, file := range torrent.Files {
+		fmt.Printf("   %s (%s)\n", file.Path, file.SizeRaw)
+	}
+	if len(torrent.Flags) > 0 {
+		fmt.Printf("⚠️ Suspicious : %s\n", strings.Join(torrent.Flags, ", "))
+	}
 	fmt.Println()
 }
 
@@ -69,6 +89,8 @@ func (dd *DebugDisplay) PrintSeedScoreDebug(torrent parser.TorrentFile) {
 	fmt.Printf("📦 Torrent Debug Report\n")
 	fmt.Printf("🔤 Name:        %s\n", torrent.Name)
 	fmt.Printf("🚀 Seeders    : %d\n", torrent.Seeders)
+	fmt.Printf("🧭 Sites      : %s\n", sitesLabel(torrent))
+	fmt.Printf("🛰️ Mirror     : %s\n", torrent.Mirror)
 	fmt.Printf("🩸 Leechers   : %d\n", torrent.Leechers)
 	fmt.Printf("🏅 Seed Score:  %.2f / 20\n", score)
 	fmt.Println("⚓------------------------------")
@@ -86,6 +108,7 @@ func (dd *DebugDisplay) PrintTorrentScoreDebug(torrent parser.TorrentFile) {
 	fmt.Printf("🖥 Resolution Score  : %.2f / 20\n", dd.ranker.ResolutionScore)
 	fmt.Printf("🎞 Source Score      : %.2f / 15\n", dd.ranker.SourceScore)
 	fmt.Printf("🎧 Codecs Score      : %.2f / 7\n", dd.ranker.CodecsScore)
+	fmt.Printf("🔆 HDR Score         : %.2f / 2\n", dd.ranker.HDRScore)
 	fmt.Printf("🧑‍🚀 Uploader Score   : %.2f / 3\n", dd.ranker.UploaderScore)
 	fmt.Println("───────────────────────────────────────")
 	fmt.Printf("🏁 TOTAL SCORE       : %.2f / 100\n", torrentScore)
@@ -100,32 +123,193 @@ func (dm *DisplayManager) ListTorrents() {
 
 	fmt.Println("Ranked Torrent List:")
 	fmt.Println(
-		"---------------------------------------------------------------------------------------------",
+		"------------------------------------------------------------------------------------------------------------------------------------",
 	)
 	fmt.Printf(
-		"%-3s %-50s %-7s %-8s %-6s %-6s\n",
+		"%-3s %-50s %-7s %-8s %-14s %-6s %-14s %-8s %-6s\n",
 		"#",
 		"Name",
 		"Size(GB)",
 		"Seeders",
+		"Sites",
 		"Res",
+		"HDR",
+		"Audio",
 		"Score",
 	)
 	fmt.Println(
-		"---------------------------------------------------------------------------------------------",
+		"------------------------------------------------------------------------------------------------------------------------------------",
 	)
 
 	// Display Each Torrent
 	for i, torrent := range dm.torrents {
-		fmt.Printf("%-3d %-50s %-7.2f %-8d %-6s %-6.2f\n",
+		fmt.Printf("%-3d %-50s %-7.2f %-8d %-14s %-6s %-14s %-8s %-6.2f\n",
 			i+1,
 			truncateString(torrent.Name, 50),
 			torrent.Size, // Convert bytes to GB
 			torrent.Seeders,
+			truncateString(sitesLabel(*torrent), 14),
 			torrent.Resolution,
+			hdrLabel(torrent.HDRFormat),
+			audioLabel(*torrent),
 			torrent.Score,
 		)
 	}
+
+	for i, torrent := range dm.torrents {
+		if len(torrent.Flags) > 0 {
+			fmt.Printf("⚠️  #%d looks suspicious: %s\n", i+1, strings.Join(torrent.Flags, ", "))
+		}
+	}
+}
+
+// PrintProgress redraws a single line progress bar, it ends the line once done == total
+func PrintProgress(done int, total int) {
+	const width = 30
+
+	if total <= 0 {
+		return
+	}
+
+	filled := done * width / total
+	fmt.Printf("\r⏳ Fetching details [%s%s] %d/%d",
+		strings.Repeat("█", filled),
+		strings.Repeat("░", width-filled),
+		done,
+		total,
+	)
+
+	if done >= total {
+		fmt.Println()
+	}
+}
+
+// PrintHealthReport prints one parser diagnosis, failed checks first catch the eye
+func PrintHealthReport(report parser.HealthReport) {
+	status := "✅ healthy"
+	if !report.Healthy {
+		status = "❌ broken"
+	}
+
+	fmt.Printf("🩺 %s (%s): %s\n", report.Site, report.Source, status)
+	for _, check := range report.Checks {
+		mark := "✔"
+		if !check.OK {
+			mark = "✘"
+		}
+		fmt.Printf("   %s %-18s %s\n", mark, check.Name, check.Detail)
+	}
+}
+
+// PrintMirrorTable prints every probed mirror, the ones picked for their site are starred
+// and latencies marked with * were remembered by the mirror registry
+func PrintMirrorTable(results []sites.MirrorResult, picked []*sites.MirrorResult) {
+	fmt.Printf("   %-10s %-40s %-13s %-6s %-9s %s\n", "Site", "Mirror", "Status", "Code", "Latency", "Error")
+	fmt.Println("------------------------------------------------------------------------------------------------------------")
+
+	for i, result := range results {
+		mark := "  "
+		for _, p := range picked {
+			if p == &results[i] {
+				mark = "⭐"
+			}
+		}
+
+		code, latency, errText := "-", "-", ""
+		if result.StatusCode != 0 {
+			code = fmt.Sprint(result.StatusCode)
+		}
+		if result.Status != sites.MirrorSkipped && result.Latency > 0 {
+			latency = result.Latency.Round(time.Millisecond).String()
+		}
+		if result.Cached {
+			latency += "*"
+		}
+		if result.Err != nil {
+			errText = truncateString(result.Err.Error(), 60)
+		}
+
+		fmt.Printf("%s %-10s %-40s %-13s %-6s %-9s %s\n",
+			mark,
+			truncateString(result.SiteName, 10),
+			truncateString(result.Mirror, 40),
+			result.Status,
+			code,
+			latency,
+			errText,
+		)
+	}
+}
+
+// PrintTorrentMeta prints what a .torrent file holds, the inspect command's output
+func PrintTorrentMeta(torrent *metainfo.Torrent) {
+	fmt.Printf("📦 Name       : %s\n", torrent.Name)
+	if torrent.InfoHash != "" {
+		fmt.Printf("#️⃣ InfoHash   : %s\n", torrent.InfoHash)
+	}
+	if torrent.InfoHashV2 != "" {
+		fmt.Printf("#️⃣ InfoHash v2: %s\n", torrent.InfoHashV2)
+	}
+	fmt.Printf("📁 Size       : %s (%d bytes)\n", formatBytes(torrent.TotalSize), torrent.TotalSize)
+	fmt.Printf("🧩 Pieces     : %d x %s\n", torrent.Pieces, formatBytes(torrent.PieceLength))
+	fmt.Printf("🔒 Private    : %t\n", torrent.Private)
+	if !torrent.CreationDate.IsZero() {
+		fmt.Printf("📅 Created    : %s\n", torrent.CreationDate.Format("2006-01-02 15:04"))
+	}
+	if torrent.CreatedBy != "" {
+		fmt.Printf("🛠️ Created by : %s\n", torrent.CreatedBy)
+	}
+	if torrent.Comment != "" {
+		fmt.Printf("💬 Comment    : %s\n", torrent.Comment)
+	}
+
+	fmt.Printf("📡 Trackers   : %d\n", len(torrent.Trackers))
+	for _, tracker := range torrent.Trackers {
+		fmt.Printf("   %s\n", tracker)
+	}
+
+	fmt.Printf("🗂️ Files      : %d\n", len(torrent.Files))
+	for _, file := range torrent.Files {
+		fmt.Printf("   %-10s %s\n", formatBytes(file.Length), file.Path)
+	}
+
+	fmt.Printf("🧲 Magnet     : %s\n", torrent.Magnet())
+}
+
+// formatBytes writes a byte count in the largest binary unit that keeps it above 1
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit {
+			return fmt.Sprintf("%.2f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%.2f TB", value/unit)
+}
+
+func hdrLabel(format string) string {
+	if format == "" {
+		return "SDR"
+	}
+	return format
+}
+
+// audioLabel is the widest channel layout plus the track count when there is more than one
+func audioLabel(torrent parser.TorrentFile) string {
+	label := torrent.AudioChannels
+	if label == "" {
+		label = "?"
+	}
+	if torrent.AudioTracks > 1 {
+		label += fmt.Sprintf(" x%d", torrent.AudioTracks)
+	}
+	return label
 }
 
 func truncateString(str string, maxLen int) string {
@@ -134,3 +318,11 @@ func truncateString(str string, maxLen int) string {
 	}
 	return str[:maxLen-3] + "..."
 }
+
+// sitesLabel lists every site a torrent was found on
+func sitesLabel(torrent parser.TorrentFile) string {
+	if len(torrent.Sources) == 0 {
+		return torrent.SiteName
+	}
+	return strings.Join(torrent.Sources, "+")
+}
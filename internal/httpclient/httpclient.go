@@ -0,0 +1,227 @@
+// Package httpclient is the one HTTP client every parser and the mirror checks go through.
+// It adds connection timeouts, retries with jittered backoff on network errors and 5xx,
+// honors Retry-After on 429 and spaces out requests to the same host.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/114.0.0.0 Safari/537.36"
+
+type Options struct {
+	Timeout           time.Duration // whole request incl. body, a last resort when ctx has no deadline
+	DialTimeout       time.Duration
+	MaxRetries        int           // extra attempts after the first one
+	BaseBackoff       time.Duration // doubled on every retry, with jitter
+	MaxBackoff        time.Duration // also caps how long a Retry-After is honored
+	RequestsPerSecond float64       // per host, 0 means unlimited
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Timeout:           60 * time.Second,
+		DialTimeout:       10 * time.Second,
+		MaxRetries:        2,
+		BaseBackoff:       500 * time.Millisecond,
+		MaxBackoff:        30 * time.Second,
+		RequestsPerSecond: 2,
+	}
+}
+
+type Client struct {
+	opts   Options
+	client *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]time.Time // host -> earliest time the next request may start
+}
+
+func New(opts Options) *Client {
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.DialTimeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &Client{
+		opts: opts,
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+		},
+		hosts: make(map[string]time.Time),
+	}
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient = New(DefaultOptions())
+)
+
+// Default returns the shared client
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
+
+// Configure replaces the shared client, call it once at startup
+func Configure(opts Options) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = New(opts)
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", url, err)
+	}
+	return c.Do(req)
+}
+
+// Do sends req, retrying network errors other than timeouts, 5xx and 429. Only body-less requests are
+// retried safely, which is all KrakenEye sends. The last response is returned as is,
+// checking its status is up to the caller.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForHost(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Do(req)
+		if attempt >= c.opts.MaxRetries || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && resp.StatusCode == http.StatusTooManyRequests {
+				delay = min(retryAfter, c.opts.MaxBackoff)
+			}
+			log.Printf("🔁 %s %s returned %d, retrying in %s", req.Method, req.URL.Host, resp.StatusCode, delay.Round(time.Millisecond))
+			drainAndClose(resp)
+		} else {
+			log.Printf("🔁 %s %s failed (%v), retrying in %s", req.Method, req.URL.Host, err, delay.Round(time.Millisecond))
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// the caller gave up, trying again won't help
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		// a timeout already used up Timeout or DialTimeout, retrying would multiply it
+		var netErr net.Error
+		return !errors.As(err, &netErr) || !netErr.Timeout()
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff is BaseBackoff * 2^attempt with up to 50% jitter either way, capped at MaxBackoff
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.opts.BaseBackoff << attempt
+	if delay <= 0 || delay > c.opts.MaxBackoff {
+		delay = c.opts.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)+1)) - delay/2
+	return max(delay+jitter, 0)
+}
+
+// parseRetryAfter understands both delta-seconds and an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// waitForHost blocks until host may get another request under RequestsPerSecond
+func (c *Client) waitForHost(ctx context.Context, host string) error {
+	if c.opts.RequestsPerSecond <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / c.opts.RequestsPerSecond)
+
+	// a request that won't be sent must not hold up the ones after it
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.hosts[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.hosts[host] = slot.Add(interval)
+	c.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		// hand the slot back when nobody queued up behind it
+		c.mu.Lock()
+		if c.hosts[host].Equal(slot.Add(interval)) {
+			c.hosts[host] = slot
+		}
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if err := resp.Body.Close(); err != nil {
+		log.Printf("⚠️ Warning: failed to close response body: %v", err)
+	}
+}
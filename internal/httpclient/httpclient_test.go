@@ -0,0 +1,231 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers with handler and counts the requests it got
+func countingServer(t *testing.T, handler func(w http.ResponseWriter, attempt int32)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, requests.Add(1))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testOptions() Options {
+	return Options{
+		Timeout:     5 * time.Second,
+		DialTimeout: time.Second,
+		MaxRetries:  2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("7"); !ok || delay != 7*time.Second {
+		t.Errorf("seconds: got %v %t, want 7s", delay, ok)
+	}
+
+	date := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date); !ok || delay < 85*time.Second || delay > 90*time.Second {
+		t.Errorf("HTTP date: got %v %t, want about 90s", delay, ok)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(past); !ok || delay != 0 {
+		t.Errorf("past date: got %v %t, want 0", delay, ok)
+	}
+
+	for _, value := range []string{"", "-3", "soon"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Errorf("parseRetryAfter(%q) accepted", value)
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	start := time.Now()
+	resp, err := New(testOptions()).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode, requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After's 1s", elapsed)
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// an hour is more than MaxBackoff, which caps it
+	opts := testOptions()
+	opts.MaxBackoff = 100 * time.Millisecond
+
+	start := time.Now()
+	resp, err := New(opts).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	elapsed := time.Since(start)
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode, requests.Load())
+	}
+	if elapsed < opts.MaxBackoff || elapsed > 5*time.Second {
+		t.Errorf("retried after %v, want MaxBackoff's %v", elapsed, opts.MaxBackoff)
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	resp, err := New(testOptions()).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want the last 503", resp.StatusCode)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("%d requests, want the first one and MaxRetries 2", got)
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusBadRequest} {
+		server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+			w.WriteHeader(status)
+		})
+
+		resp, err := New(testOptions()).Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := requests.Load(); got != 1 || resp.StatusCode != status {
+			t.Errorf("%d: %d requests and status %d, want one request", status, got, resp.StatusCode)
+		}
+	}
+}
+
+func TestNoRetryOnTimeout(t *testing.T) {
+	server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+		time.Sleep(300 * time.Millisecond)
+	})
+
+	opts := testOptions()
+	opts.Timeout = 50 * time.Millisecond
+
+	if _, err := New(opts).Get(context.Background(), server.URL); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests, a timeout must not be retried", got)
+	}
+}
+
+func TestPerHostSpacing(t *testing.T) {
+	server, requests := countingServer(t, func(w http.ResponseWriter, attempt int32) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	opts := testOptions()
+	opts.RequestsPerSecond = 20 // one every 50ms
+	client := New(opts)
+
+	start := time.Now()
+	for range 4 {
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("%d requests took %v, want at least 150ms at 20 per second", requests.Load(), elapsed)
+	}
+}
+
+func TestCancelledRequestKeepsNoSlot(t *testing.T) {
+	opts := testOptions()
+	opts.RequestsPerSecond = 1
+	client := New(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.waitForHost(ctx, "example.invalid"); err == nil {
+		t.Fatal("waitForHost went through with a cancelled context")
+	}
+
+	start := time.Now()
+	if err := client.waitForHost(context.Background(), "example.invalid"); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("waited %v behind a cancelled request", waited)
+	}
+}
+
+func TestCancelledWaitGivesSlotBack(t *testing.T) {
+	opts := testOptions()
+	opts.RequestsPerSecond = 2 // one every 500ms
+	client := New(opts)
+
+	// takes the free slot
+	if err := client.waitForHost(context.Background(), "example.invalid"); err != nil {
+		t.Fatal(err)
+	}
+
+	// queues for the next one, then gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.waitForHost(ctx, "example.invalid"); err == nil {
+		t.Fatal("waitForHost outlived its context")
+	}
+
+	start := time.Now()
+	if err := client.waitForHost(context.Background(), "example.invalid"); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > 700*time.Millisecond {
+		t.Errorf("waited %v, the abandoned slot was kept", waited)
+	}
+}
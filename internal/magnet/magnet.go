@@ -0,0 +1,150 @@
+// Package magnet parses magnet URIs and normalizes the info-hash they carry, so the same
+// torrent can be recognised whether a site writes its hash in hex or base32.
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotMagnet   = errors.New("not a magnet link")
+	ErrNoInfoHash  = errors.New("magnet link has no info-hash")
+	ErrBadInfoHash = errors.New("malformed info-hash")
+)
+
+// Link is a parsed magnet URI
+type Link struct {
+	InfoHash   string // BitTorrent v1 btih, 40 lowercase hex characters, empty for v2 only links
+	InfoHashV2 string // BitTorrent v2 btmh, the sha2-256 multihash in lowercase hex
+	Name       string // dn
+	Trackers   []string
+	Length     int64 // xl in bytes, 0 when not given
+}
+
+// Parse reads a magnet URI. It fails when the URI is no magnet link or carries no usable
+// BitTorrent info-hash.
+func Parse(uri string) (Link, error) {
+	var link Link
+
+	rest, ok := strings.CutPrefix(strings.TrimSpace(uri), "magnet:?")
+	if !ok {
+		return link, ErrNotMagnet
+	}
+
+	// a stray unescaped % in dn is common, the pairs that did parse are still good
+	params, err := url.ParseQuery(rest)
+	if err != nil && len(params) == 0 {
+		return link, fmt.Errorf("%w: %v", ErrNotMagnet, err)
+	}
+
+	// clients number repeated keys xt.1, xt.2 ...
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		base, _, _ := strings.Cut(key, ".")
+		for _, value := range params[key] {
+			switch base {
+			case "xt":
+				if err := link.parseExactTopic(value); err != nil {
+					return link, err
+				}
+			case "dn":
+				if link.Name == "" {
+					link.Name = value
+				}
+			case "tr":
+				link.Trackers = append(link.Trackers, value)
+			case "xl":
+				link.Length, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+	}
+
+	if link.InfoHash == "" && link.InfoHashV2 == "" {
+		return link, ErrNoInfoHash
+	}
+	return link, nil
+}
+
+// parseExactTopic reads one xt value, topics other than BitTorrent ones are ignored
+func (l *Link) parseExactTopic(topic string) error {
+	lower := strings.ToLower(topic)
+
+	switch {
+	case strings.HasPrefix(lower, "urn:btih:"):
+		hash, err := NormalizeInfoHash(topic[len("urn:btih:"):])
+		if err != nil {
+			return err
+		}
+		l.InfoHash = hash
+
+	case strings.HasPrefix(lower, "urn:btmh:"):
+		// 0x12 sha2-256, 0x20 bytes long, then the digest
+		hash := lower[len("urn:btmh:"):]
+		if len(hash) != 68 || !strings.HasPrefix(hash, "1220") || !isHex(hash) {
+			return fmt.Errorf("%w: btmh %q", ErrBadInfoHash, hash)
+		}
+		l.InfoHashV2 = hash
+	}
+
+	return nil
+}
+
+// NormalizeInfoHash turns a v1 info-hash in hex or base32 into 40 lowercase hex characters
+func NormalizeInfoHash(hash string) (string, error) {
+	hash = strings.TrimSpace(hash)
+
+	switch len(hash) {
+	case 40:
+		if isHex(hash) {
+			return strings.ToLower(hash), nil
+		}
+	case 32:
+		raw, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err == nil && len(raw) == 20 {
+			return hex.EncodeToString(raw), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: btih %q", ErrBadInfoHash, hash)
+}
+
+// Hash is the v1 info-hash, or the v2 one for v2 only torrents
+func (l Link) Hash() string {
+	if l.InfoHash != "" {
+		return l.InfoHash
+	}
+	return l.InfoHashV2
+}
+
+// String writes the link back as a magnet URI with its hashes normalized
+func (l Link) String() string {
+	var parts []string
+	if l.InfoHash != "" {
+		parts = append(parts, "xt=urn:btih:"+l.InfoHash)
+	}
+	if l.InfoHashV2 != "" {
+		parts = append(parts, "xt=urn:btmh:"+l.InfoHashV2)
+	}
+	if l.Name != "" {
+		parts = append(parts, "dn="+url.QueryEscape(l.Name))
+	}
+	if l.Length > 0 {
+		parts = append(parts, "xl="+strconv.FormatInt(l.Length, 10))
+	}
+	for _, tracker := range l.Trackers {
+		parts = append(parts, "tr="+url.QueryEscape(tracker))
+	}
+	return "magnet:?" + strings.Join(parts, "&")
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package magnet
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	hexHash    = "0123456789abcdef0123456789abcdef01234567"
+	base32Hash = "AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH"
+	btmhHash   = "1220" + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want Link
+		err  error
+	}{
+		{"hex btih", "magnet:?xt=urn:btih:" + hexHash, Link{InfoHash: hexHash}, nil},
+		{"uppercase hex btih", "magnet:?xt=urn:btih:" + strings.ToUpper(hexHash), Link{InfoHash: hexHash}, nil},
+		{"base32 btih", "magnet:?xt=urn:btih:" + base32Hash, Link{InfoHash: hexHash}, nil},
+		{"lowercase base32 btih", "magnet:?xt=urn:btih:" + strings.ToLower(base32Hash), Link{InfoHash: hexHash}, nil},
+		{"uppercase urn", "magnet:?xt=URN:BTIH:" + hexHash, Link{InfoHash: hexHash}, nil},
+		{"btmh only", "magnet:?xt=urn:btmh:" + btmhHash, Link{InfoHashV2: btmhHash}, nil},
+		{
+			"hybrid with numbered xt",
+			"magnet:?xt.1=urn:btih:" + hexHash + "&xt.2=urn:btmh:" + btmhHash,
+			Link{InfoHash: hexHash, InfoHashV2: btmhHash},
+			nil,
+		},
+		{
+			"dn xl and trackers",
+			"magnet:?xt=urn:btih:" + hexHash + "&dn=The+Matrix+1999&xl=2147483648&tr=udp%3A%2F%2Ftracker.invalid%3A1337&tr=https%3A%2F%2Fb.invalid%2Fannounce",
+			Link{InfoHash: hexHash, Name: "The Matrix 1999", Length: 2147483648, Trackers: []string{"udp://tracker.invalid:1337", "https://b.invalid/announce"}},
+			nil,
+		},
+		{"stray percent in dn", "magnet:?xt=urn:btih:" + hexHash + "&dn=100%+Real", Link{InfoHash: hexHash}, nil},
+		{"other topics ignored", "magnet:?xt=urn:sha1:ABC&xt=urn:btih:" + hexHash, Link{InfoHash: hexHash}, nil},
+
+		{"not a magnet", "https://example.invalid/file.torrent", Link{}, ErrNotMagnet},
+		{"empty", "", Link{}, ErrNotMagnet},
+		{"no info-hash", "magnet:?dn=The+Matrix", Link{}, ErrNoInfoHash},
+		{"only foreign topics", "magnet:?xt=urn:ed2k:31D6CFE0D16AE931B73C59D7E0C089C0", Link{}, ErrNoInfoHash},
+		{"short hex", "magnet:?xt=urn:btih:" + hexHash[:39], Link{}, ErrBadInfoHash},
+		{"long hex", "magnet:?xt=urn:btih:" + hexHash + "0", Link{}, ErrBadInfoHash},
+		{"bad hex character", "magnet:?xt=urn:btih:" + hexHash[:39] + "g", Link{}, ErrBadInfoHash},
+		{"bad base32 character", "magnet:?xt=urn:btih:" + base32Hash[:31] + "1", Link{}, ErrBadInfoHash},
+		{"short btmh", "magnet:?xt=urn:btmh:" + btmhHash[:66], Link{}, ErrBadInfoHash},
+		{"btmh with another hash function", "magnet:?xt=urn:btmh:1320" + btmhHash[4:], Link{}, ErrBadInfoHash},
+		{"bad btmh character", "magnet:?xt=urn:btmh:" + btmhHash[:67] + "z", Link{}, ErrBadInfoHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := Parse(tt.uri)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.uri, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.uri, err)
+			}
+			if tt.want.Name == "" {
+				link.Name = ""
+			}
+			if !reflect.DeepEqual(link, tt.want) {
+				t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.uri, link, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashAndString(t *testing.T) {
+	link, err := Parse("magnet:?xt=urn:btih:" + base32Hash + "&dn=The Matrix&xl=10&tr=udp://a.invalid:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "magnet:?xt=urn:btih:" + hexHash + "&dn=The+Matrix&xl=10&tr=udp%3A%2F%2Fa.invalid%3A1"
+	if got := link.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if link.Hash() != hexHash {
+		t.Errorf("Hash() = %q", link.Hash())
+	}
+	if v2 := (Link{InfoHashV2: btmhHash}); v2.Hash() != btmhHash {
+		t.Errorf("Hash() of a v2 only link = %q", v2.Hash())
+	}
+}
@@ -0,0 +1,109 @@
+package magnet
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultMaxTrackers caps how many trackers an augmented magnet carries, some clients choke
+// on very long URIs
+const DefaultMaxTrackers = 30
+
+// TrackerList is a user maintained list of trackers added to every magnet link. The zero
+// value adds nothing.
+type TrackerList struct {
+	Trackers []string
+	Max      int // trackers a magnet ends up with at most, its own ones always stay
+}
+
+// LoadTrackerList reads one announce URL per line, blank lines and # comments are skipped,
+// and so are duplicates and anything that is no udp, http(s) or wss URL
+func LoadTrackerList(path string, max int) (TrackerList, error) {
+	list := TrackerList{Max: max}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return list, fmt.Errorf("failed to read tracker list: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !isTrackerURL(line) || seen[trackerKey(line)] {
+			continue
+		}
+		seen[trackerKey(line)] = true
+		list.Trackers = append(list.Trackers, line)
+	}
+
+	return list, nil
+}
+
+// Augment adds the list's trackers to a magnet URI. The URI is only appended to, so web seeds,
+// peers and anything else Link doesn't know survive. URIs that don't parse come back as they are.
+func (t TrackerList) Augment(uri string) string {
+	if len(t.Trackers) == 0 {
+		return uri
+	}
+
+	link, err := Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	own := len(link.Trackers)
+	link.AddTrackers(t.Trackers, t.Max)
+
+	var b strings.Builder
+	b.WriteString(uri)
+	for _, tracker := range link.Trackers[own:] {
+		b.WriteString("&tr=")
+		b.WriteString(url.QueryEscape(tracker))
+	}
+	return b.String()
+}
+
+// AddTrackers appends trackers the link doesn't have yet until it carries max of them,
+// max <= 0 means no cap
+func (l *Link) AddTrackers(trackers []string, max int) {
+	seen := make(map[string]bool)
+	for _, tracker := range l.Trackers {
+		seen[trackerKey(tracker)] = true
+	}
+
+	for _, tracker := range trackers {
+		if max > 0 && len(l.Trackers) >= max {
+			return
+		}
+		if seen[trackerKey(tracker)] {
+			continue
+		}
+		seen[trackerKey(tracker)] = true
+		l.Trackers = append(l.Trackers, tracker)
+	}
+}
+
+// trackerKey is what two spellings of the same tracker have in common
+func trackerKey(tracker string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(tracker)), "/")
+}
+
+func isTrackerURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "udp", "http", "https", "wss":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package magnet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadTrackerList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trackers.txt")
+	data := `# public trackers
+udp://tracker.opentrackr.org:1337/announce
+
+  https://tracker.invalid/announce
+# udp://commented.invalid:80/announce
+UDP://TRACKER.OPENTRACKR.ORG:1337/announce/
+wss://tracker.webtorrent.invalid
+ftp://files.invalid/announce
+not a tracker
+udp://
+http:///announce
+https://tracker.invalid/announce
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadTrackerList(path, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"udp://tracker.opentrackr.org:1337/announce",
+		"https://tracker.invalid/announce",
+		"wss://tracker.webtorrent.invalid",
+	}
+	if !reflect.DeepEqual(list.Trackers, want) || list.Max != 20 {
+		t.Errorf("list = %v max %d, want %v max 20", list.Trackers, list.Max, want)
+	}
+
+	if _, err := LoadTrackerList(filepath.Join(t.TempDir(), "missing.txt"), 20); err == nil {
+		t.Error("a missing file loaded")
+	}
+}
+
+func TestAugment(t *testing.T) {
+	list := TrackerList{Trackers: []string{"udp://a.invalid:1", "udp://b.invalid:2/announce", "udp://c.invalid:3"}}
+
+	tests := []struct {
+		name string
+		list TrackerList
+		uri  string
+		want string
+	}{
+		{
+			"adds the missing trackers",
+			list,
+			"magnet:?xt=urn:btih:" + hexHash + "&dn=The+Matrix",
+			"magnet:?xt=urn:btih:" + hexHash + "&dn=The+Matrix&tr=udp%3A%2F%2Fa.invalid%3A1&tr=udp%3A%2F%2Fb.invalid%3A2%2Fannounce&tr=udp%3A%2F%2Fc.invalid%3A3",
+		},
+		{
+			"keeps the params Link doesn't know",
+			list,
+			"magnet:?xt=urn:btih:" + hexHash + "&xt=urn:btmh:" + btmhHash + "&dn=The+Matrix&ws=https%3A%2F%2Fseed.invalid%2Fmatrix.mkv&xs=https%3A%2F%2Fx.invalid%2Fm.torrent&as=https%3A%2F%2Fas.invalid%2Fm&x.pe=10.0.0.1%3A6881&so=0-2",
+			"magnet:?xt=urn:btih:" + hexHash + "&xt=urn:btmh:" + btmhHash + "&dn=The+Matrix&ws=https%3A%2F%2Fseed.invalid%2Fmatrix.mkv&xs=https%3A%2F%2Fx.invalid%2Fm.torrent&as=https%3A%2F%2Fas.invalid%2Fm&x.pe=10.0.0.1%3A6881&so=0-2" +
+				"&tr=udp%3A%2F%2Fa.invalid%3A1&tr=udp%3A%2F%2Fb.invalid%3A2%2Fannounce&tr=udp%3A%2F%2Fc.invalid%3A3",
+		},
+		{
+			"skips trackers the magnet has",
+			list,
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=UDP%3A%2F%2FA.invalid%3A1%2F",
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=UDP%3A%2F%2FA.invalid%3A1%2F&tr=udp%3A%2F%2Fb.invalid%3A2%2Fannounce&tr=udp%3A%2F%2Fc.invalid%3A3",
+		},
+		{
+			"stops at max counting the magnet's own",
+			TrackerList{Trackers: list.Trackers, Max: 2},
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=udp%3A%2F%2Fown.invalid%3A9",
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=udp%3A%2F%2Fown.invalid%3A9&tr=udp%3A%2F%2Fa.invalid%3A1",
+		},
+		{
+			"magnet already at max",
+			TrackerList{Trackers: list.Trackers, Max: 1},
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=udp%3A%2F%2Fown.invalid%3A9",
+			"magnet:?xt=urn:btih:" + hexHash + "&tr=udp%3A%2F%2Fown.invalid%3A9",
+		},
+		{"empty list", TrackerList{}, "magnet:?xt=urn:btih:" + hexHash + "&ws=x", "magnet:?xt=urn:btih:" + hexHash + "&ws=x"},
+		{"not a magnet", list, "https://example.invalid/file.torrent", "https://example.invalid/file.torrent"},
+		{"bad info-hash", list, "magnet:?xt=urn:btih:1234", "magnet:?xt=urn:btih:1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.list.Augment(tt.uri); got != tt.want {
+				t.Errorf("Augment(%q)\n got  %q\n want %q", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTrackers(t *testing.T) {
+	link := Link{InfoHash: hexHash, Trackers: []string{"udp://a.invalid:1/"}}
+	link.AddTrackers([]string{"UDP://A.invalid:1", "udp://b.invalid:2", "udp://c.invalid:3"}, 2)
+
+	want := []string{"udp://a.invalid:1/", "udp://b.invalid:2"}
+	if !reflect.DeepEqual(link.Trackers, want) {
+		t.Errorf("trackers = %v, want %v", link.Trackers, want)
+	}
+}
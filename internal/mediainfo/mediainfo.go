@@ -0,0 +1,375 @@
+// Package mediainfo reads the text report MediaInfo prints, the one uploaders paste into
+// torrent descriptions:
+//
+//	Video
+//	Format                                   : HEVC
+//	Width                                    : 3 840 pixels
+//
+// Only the fields that say something about quality are kept.
+package mediainfo
+
+import (
+	"fmt"
+	"regexp"
+	"sanjaix21/krakeneye/internal/release"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report is what a MediaInfo dump says about a release, anything it leaves out stays zero
+type Report struct {
+	Container      string        // General "Format": Matroska, MPEG-4 ...
+	Runtime        time.Duration // of the whole file
+	OverallBitrate int           // kb/s
+	Video          Video
+	Audio          []Audio
+	Subtitles      []Subtitle
+}
+
+// Video is the first video track
+type Video struct {
+	Format         string // HEVC, AVC, AV1 ...
+	Profile        string // Main 10@L5.1@High
+	Width          int
+	Height         int
+	FrameRate      float64
+	Bitrate        int // kb/s
+	BitDepth       int
+	HDRFormat      string // MediaInfo's own "HDR format" line
+	Transfer       string // PQ, HLG, BT.709 ...
+	ColorPrimaries string
+}
+
+type Audio struct {
+	Format         string // E-AC-3, MLP FBA, DTS XLL ...
+	CommercialName string // Dolby Digital Plus, Dolby TrueHD with Dolby Atmos ...
+	Channels       string // 2.0, 5.1, 7.1 ...
+	Bitrate        int    // kb/s
+	Language       string
+	Title          string
+}
+
+type Subtitle struct {
+	Format   string // UTF-8, PGS, ASS ...
+	Language string
+	Forced   bool
+}
+
+var (
+	sectionPattern  = regexp.MustCompile(`^(General|Video|Audio|Text|Menu|Image|Other)(?: #\d+)?$`)
+	durationPattern = regexp.MustCompile(`(\d+)\s*(ms|min|h|s)\b`)
+	clockPattern    = regexp.MustCompile(`^(\d{1,2}):(\d{2}):(\d{2})`)
+	bitratePattern  = regexp.MustCompile(`^([\d ]+(?:\.\d+)?)\s*([kM])b/s`)
+	leadingNumber   = regexp.MustCompile(`^[\d ]*\d(?:\.\d+)?`)
+
+	// the speaker names MediaInfo writes in a channel layout: L R C LFE Ls Rs Lb Rb Tfl ...
+	channelName = regexp.MustCompile(`^(?:[LRCM]|LFE\d?|[LR][sbwct]|[LR](?:rs|ss|vh)|C[bs]|Vh[lr]|T(?:[fbs][lcr]|c))$`)
+)
+
+// Parse reads a MediaInfo report out of text. ok is false when text holds none, a
+// description that just mentions "Video: HEVC" is not a report.
+func Parse(text string) (report Report, ok bool) {
+	section := ""
+	var audio *Audio
+	var subtitle *Subtitle
+	videoTracks := 0
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "\u00a0", " "))
+
+		if match := sectionPattern.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			switch section {
+			case "Video":
+				videoTracks++
+			case "Audio":
+				report.Audio = append(report.Audio, Audio{})
+				audio = &report.Audio[len(report.Audio)-1]
+			case "Text":
+				report.Subtitles = append(report.Subtitles, Subtitle{})
+				subtitle = &report.Subtitles[len(report.Subtitles)-1]
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch section {
+		case "General":
+			report.parseGeneral(key, value)
+		case "Video":
+			// a second video track is usually a cover or the DV enhancement layer
+			if videoTracks == 1 {
+				report.Video.parse(key, value)
+			}
+		case "Audio":
+			audio.parse(key, value)
+		case "Text":
+			subtitle.parse(key, value)
+		}
+	}
+
+	ok = report.Video.Format != "" || report.Video.Height > 0 || len(report.Audio) > 0
+	return report, ok
+}
+
+func (r *Report) parseGeneral(key string, value string) {
+	switch key {
+	case "format":
+		r.Container = value
+	case "duration":
+		r.Runtime = parseDuration(value)
+	case "overall bit rate":
+		r.OverallBitrate = parseBitrate(value)
+	}
+}
+
+func (v *Video) parse(key string, value string) {
+	switch key {
+	case "format":
+		v.Format = value
+	case "format profile":
+		v.Profile = value
+	case "width":
+		v.Width = int(parseNumber(value))
+	case "height":
+		v.Height = int(parseNumber(value))
+	case "frame rate":
+		v.FrameRate = parseNumber(value)
+	case "bit rate":
+		v.Bitrate = parseBitrate(value)
+	case "bit depth":
+		v.BitDepth = int(parseNumber(value))
+	case "hdr format":
+		v.HDRFormat = value
+	case "transfer characteristics":
+		v.Transfer = value
+	case "color primaries":
+		v.ColorPrimaries = value
+	}
+}
+
+func (a *Audio) parse(key string, value string) {
+	switch key {
+	case "format":
+		a.Format = value
+	case "commercial name":
+		a.CommercialName = value
+	case "channel(s)":
+		if a.Channels == "" {
+			a.Channels = channelLayout(channelCount(value), "")
+		}
+	case "channel layout":
+		// only the layout knows whether one of the channels is the LFE
+		if count, layout := layoutChannels(value); count > 0 {
+			a.Channels = channelLayout(count, layout)
+		}
+	case "bit rate":
+		a.Bitrate = parseBitrate(value)
+	case "language":
+		a.Language = release.NormalizeLanguage(value)
+	case "title":
+		a.Title = value
+	}
+}
+
+func (s *Subtitle) parse(key string, value string) {
+	switch key {
+	case "format":
+		s.Format = value
+	case "language":
+		s.Language = release.NormalizeLanguage(value)
+	case "forced":
+		s.Forced = strings.EqualFold(value, "yes")
+	}
+}
+
+// channelLayout writes a channel count the way release names do: 6 channels with an LFE
+// are 5.1. Without a layout the usual counts are guessed.
+func channelLayout(count int, layout string) string {
+	if count <= 0 {
+		return ""
+	}
+
+	lfe := strings.Contains(strings.ToUpper(layout), "LFE")
+	if layout == "" {
+		lfe = count == 6 || count == 7 || count == 8
+	}
+	if lfe {
+		return fmt.Sprintf("%d.1", count-1)
+	}
+	return fmt.Sprintf("%d.0", count)
+}
+
+// channelCount reads "6 channels", Atmos and DTS:X tracks put "Object Based / " in front
+// and older dumps add the core after a slash: "8 channels / 6 channels"
+func channelCount(value string) int {
+	for _, part := range strings.Split(value, "/") {
+		if count := int(parseNumber(strings.TrimSpace(part))); count > 0 {
+			return count
+		}
+	}
+	return 0
+}
+
+// layoutChannels counts the speakers of the first layout in value. "Object Based" isn't
+// one and a slash starts the layout of the core, so "Object Based / L R C LFE Ls Rs Lb Rb"
+// is 8 channels.
+func layoutChannels(value string) (int, string) {
+	for _, part := range strings.Split(value, "/") {
+		count := 0
+		for _, name := range strings.Fields(part) {
+			if channelName.MatchString(name) {
+				count++
+			}
+		}
+		if count > 0 {
+			return count, part
+		}
+	}
+	return 0, ""
+}
+
+// parseNumber reads the number a value starts with, MediaInfo groups thousands with a
+// space: "3 840 pixels", "23.976 (24000/1001) FPS"
+func parseNumber(value string) float64 {
+	number := strings.ReplaceAll(leadingNumber.FindString(value), " ", "")
+	n, _ := strconv.ParseFloat(number, 64)
+	return n
+}
+
+// parseBitrate turns "40.0 Mb/s" or "1 509 kb/s" into kb/s
+func parseBitrate(value string) int {
+	match := bitratePattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+
+	rate, _ := strconv.ParseFloat(strings.ReplaceAll(match[1], " ", ""), 64)
+	if match[2] == "M" {
+		rate *= 1000
+	}
+	return int(rate)
+}
+
+// parseDuration reads "2 h 16 min", "45 min 12 s" and "02:16:18.123"
+func parseDuration(value string) time.Duration {
+	if match := clockPattern.FindStringSubmatch(value); match != nil {
+		hours, _ := strconv.Atoi(match[1])
+		minutes, _ := strconv.Atoi(match[2])
+		seconds, _ := strconv.Atoi(match[3])
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	}
+
+	var duration time.Duration
+	for _, match := range durationPattern.FindAllStringSubmatch(value, -1) {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			duration += time.Duration(n) * time.Hour
+		case "min":
+			duration += time.Duration(n) * time.Minute
+		case "s":
+			duration += time.Duration(n) * time.Second
+		case "ms":
+			duration += time.Duration(n) * time.Millisecond
+		}
+	}
+	return duration
+}
+
+// Release puts the report into the release name vocabulary so it can stand in for what
+// the name guessed. Fields the report doesn't cover stay empty.
+func (r Report) Release() release.Info {
+	var info release.Info
+
+	switch {
+	case strings.HasPrefix(r.Container, "Matroska"):
+		info.Container = "Matroska/MKV"
+	case strings.HasPrefix(r.Container, "MPEG-4"):
+		info.Container = "MP4"
+	}
+
+	v := r.Video
+	info.Resolution = resolution(v.Width, v.Height)
+	switch v.Format {
+	case "MPEG Video":
+		info.VideoCodec = "MPEG-2"
+	case "MPEG-4 Visual":
+		info.VideoCodec = "XviD"
+	default:
+		info.VideoCodec = release.ParseDetails(v.Format).VideoCodec
+	}
+	if v.BitDepth > 0 {
+		info.BitDepth = fmt.Sprintf("%d-bit", v.BitDepth)
+	}
+
+	// the HDR format line uses the same words release names do
+	hdr := release.ParseDetails(v.HDRFormat + " " + v.Transfer)
+	info.HDR, info.DolbyVision, info.DVProfile = hdr.HDR, hdr.DolbyVision, hdr.DVProfile
+	if info.HDR == "" && v.Transfer == "PQ" {
+		info.HDR = "HDR"
+	}
+
+	for _, a := range r.Audio {
+		track := release.AudioTrack{Codec: a.codec(), Channels: a.Channels, Language: a.Language}
+		info.AudioTracks = append(info.AudioTracks, track)
+		if a.Language != "" && !slices.Contains(info.Languages, a.Language) {
+			info.Languages = append(info.Languages, a.Language)
+		}
+	}
+	if len(info.AudioTracks) > 0 {
+		info.AudioCodec = info.AudioTracks[0].Codec
+		info.Channels = info.AudioTracks[0].Channels
+	}
+
+	return info
+}
+
+// SubtitleLanguages lists the subtitle languages once each, forced ones included
+func (r Report) SubtitleLanguages() []string {
+	var languages []string
+	for _, s := range r.Subtitles {
+		if s.Language != "" && !slices.Contains(languages, s.Language) {
+			languages = append(languages, s.Language)
+		}
+	}
+	return languages
+}
+
+// codec maps the track to an AudioCodec value, the commercial name is the more telling one
+func (a Audio) codec() string {
+	if a.Format == "MPEG Audio" {
+		return "MP3"
+	}
+	if c := release.ParseDetails(a.CommercialName).AudioCodec; c != "" {
+		return c
+	}
+	if strings.HasPrefix(a.Format, "MLP") {
+		return "DTS-HD/TrueHD"
+	}
+	return release.ParseDetails(a.Format).AudioCodec
+}
+
+// resolution labels a frame by its width first, scope movies are 3840x1600 and still 2160P
+func resolution(width int, height int) string {
+	switch {
+	case width >= 3200 || height >= 2000:
+		return "2160P"
+	case width >= 1800 || height >= 1000:
+		return "1080P"
+	case width >= 1200 || height >= 700:
+		return "720P"
+	case width > 0 || height > 0:
+		return "480P"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,470 @@
+package mediainfo
+
+import (
+	"reflect"
+	"sanjaix21/krakeneye/internal/release"
+	"testing"
+	"time"
+)
+
+// a WEB-DL with an Atmos E-AC-3 track, a stereo commentary and subtitles
+const webAtmosDump = `General
+Unique ID                                : 201338441577383794853284120373298431022 (0x977A3A6E8F1C0D5C8F4E3E2D1C0B0A2E)
+Complete name                            : Dune.2021.2160p.HMAX.WEB-DL.DDP5.1.Atmos.HDR.HEVC-FLUX.mkv
+Format                                   : Matroska
+Format version                           : Version 4
+File size                                : 20.4 GiB
+Duration                                 : 2 h 35 min
+Overall bit rate                         : 18.8 Mb/s
+Frame rate                               : 23.976 FPS
+
+Video
+ID                                       : 1
+Format                                   : HEVC
+Format/Info                              : High Efficiency Video Coding
+Format profile                           : Main 10@L5.1@High
+HDR format                               : SMPTE ST 2086, HDR10 compatible
+Codec ID                                 : V_MPEGH/ISO/HEVC
+Duration                                 : 2 h 35 min
+Bit rate                                 : 17.9 Mb/s
+Width                                    : 3 840 pixels
+Height                                   : 1 608 pixels
+Display aspect ratio                     : 2.40:1
+Frame rate mode                          : Constant
+Frame rate                               : 23.976 (24000/1001) FPS
+Color space                              : YUV
+Chroma subsampling                       : 4:2:0
+Bit depth                                : 10 bits
+Color range                              : Limited
+Color primaries                          : BT.2020
+Transfer characteristics                 : PQ
+Matrix coefficients                      : BT.2020 non-constant
+
+Audio #1
+ID                                       : 2
+Format                                   : E-AC-3 JOC
+Format/Info                              : Enhanced AC-3 with Joint Object Coding
+Commercial name                          : Dolby Digital Plus with Dolby Atmos
+Codec ID                                 : A_EAC3
+Duration                                 : 2 h 35 min
+Bit rate mode                            : Constant
+Bit rate                                 : 768 kb/s
+Channel(s)                               : 6 channels
+Channel layout                           : L R C LFE Ls Rs
+Sampling rate                            : 48.0 kHz
+Compression mode                         : Lossy
+Language                                 : English
+Default                                  : Yes
+Forced                                   : No
+
+Audio #2
+ID                                       : 3
+Format                                   : AAC LC
+Format/Info                              : Advanced Audio Codec Low Complexity
+Codec ID                                 : A_AAC-2
+Bit rate                                 : 128 kb/s
+Channel(s)                               : 2 channels
+Channel layout                           : L R
+Sampling rate                            : 48.0 kHz
+Title                                    : Commentary
+Language                                 : English
+Default                                  : No
+
+Text #1
+ID                                       : 4
+Format                                   : UTF-8
+Codec ID                                 : S_TEXT/UTF8
+Language                                 : English
+Default                                  : No
+Forced                                   : Yes
+
+Text #2
+ID                                       : 5
+Format                                   : UTF-8
+Codec ID                                 : S_TEXT/UTF8
+Language                                 : English
+Default                                  : No
+Forced                                   : No
+
+Text #3
+ID                                       : 6
+Format                                   : PGS
+Codec ID                                 : S_HDMV/PGS
+Language                                 : Spanish
+Forced                                   : No
+`
+
+// a UHD remux with the Dolby Vision enhancement layer as a second video track, a
+// TrueHD Atmos and a DTS:X track, and a dubbed track in another language
+const remuxDVDump = `General
+Format                                   : Matroska
+Format version                           : Version 4
+File size                                : 78.2 GiB
+Duration                                 : 02:16:18.123
+Overall bit rate                         : 82.1 Mb/s
+
+Video #1
+ID                                       : 1
+Format                                   : HEVC
+Format/Info                              : High Efficiency Video Coding
+Format profile                           : Main 10@L5.1@High
+HDR format                               : Dolby Vision, Version 1.0, dvhe.07.06, BL+EL+RPU, HDR10 compatible / SMPTE ST 2086, HDR10 compatible
+Codec ID                                 : V_MPEGH/ISO/HEVC
+Bit rate                                 : 59.6 Mb/s
+Width                                    : 3 840 pixels
+Height                                   : 2 160 pixels
+Frame rate                               : 23.976 (24000/1001) FPS
+Bit depth                                : 10 bits
+Color primaries                          : BT.2020
+Transfer characteristics                 : PQ
+
+Video #2
+ID                                       : 2
+Format                                   : HEVC
+Format profile                           : Main 10@L5.1@High
+Bit rate                                 : 8 765 kb/s
+Width                                    : 1 920 pixels
+Height                                   : 1 080 pixels
+Bit depth                                : 10 bits
+
+Audio #1
+ID                                       : 3
+Format                                   : MLP FBA 16-ch
+Format/Info                              : Meridian Lossless Packing FBA with 16-channel presentation
+Commercial name                          : Dolby TrueHD with Dolby Atmos
+Codec ID                                 : A_TRUEHD
+Bit rate mode                            : Variable
+Bit rate                                 : 5 581 kb/s
+Maximum bit rate                         : 9 066 kb/s
+Channel(s)                               : 8 channels
+Channel layout                           : L R C LFE Ls Rs Lb Rb
+Sampling rate                            : 48.0 kHz
+Language                                 : English
+Default                                  : Yes
+
+Audio #2
+ID                                       : 4
+Format                                   : DTS XLL X
+Format/Info                              : Digital Theater Systems
+Commercial name                          : DTS-HD Master Audio
+Codec ID                                 : A_DTS
+Bit rate mode                            : Variable
+Bit rate                                 : 4 315 kb/s
+Channel(s)                               : Object Based / 8 channels
+Channel layout                           : Object Based / L R C LFE Ls Rs Lb Rb
+Sampling rate                            : 48.0 kHz
+Language                                 : English
+
+Audio #3
+ID                                       : 5
+Format                                   : AC-3
+Commercial name                          : Dolby Digital
+Codec ID                                 : A_AC3
+Bit rate                                 : 640 kb/s
+Channel(s)                               : 6 channels
+Channel layout                           : L R C LFE Ls Rs
+Language                                 : Hindi
+
+Text
+ID                                       : 6
+Format                                   : PGS
+Language                                 : English
+`
+
+// an old DVD rip, MPEG-4 Visual with MP3 and no color lines
+const dvdRipDump = `General
+Format                                   : AVI
+Format/Info                              : Audio Video Interleave
+File size                                : 700 MiB
+Duration                                 : 45 min 12 s
+Overall bit rate                         : 2 165 kb/s
+
+Video
+ID                                       : 0
+Format                                   : MPEG-4 Visual
+Format profile                           : Advanced Simple@L5
+Codec ID                                 : XVID
+Bit rate                                 : 1 986 kb/s
+Width                                    : 640 pixels
+Height                                   : 352 pixels
+Frame rate                               : 25.000 FPS
+Bit depth                                : 8 bits
+
+Audio
+ID                                       : 1
+Format                                   : MPEG Audio
+Format profile                           : Layer 3
+Bit rate                                 : 128 kb/s
+Channel(s)                               : 2 channels
+Language                                 : German
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		want Report
+	}{
+		{
+			"web atmos with commentary",
+			webAtmosDump,
+			Report{
+				Container:      "Matroska",
+				Runtime:        2*time.Hour + 35*time.Minute,
+				OverallBitrate: 18800,
+				Video: Video{
+					Format: "HEVC", Profile: "Main 10@L5.1@High", Width: 3840, Height: 1608, FrameRate: 23.976,
+					Bitrate: 17900, BitDepth: 10, HDRFormat: "SMPTE ST 2086, HDR10 compatible", Transfer: "PQ", ColorPrimaries: "BT.2020",
+				},
+				Audio: []Audio{
+					{Format: "E-AC-3 JOC", CommercialName: "Dolby Digital Plus with Dolby Atmos", Channels: "5.1", Bitrate: 768, Language: "English"},
+					{Format: "AAC LC", Channels: "2.0", Bitrate: 128, Language: "English", Title: "Commentary"},
+				},
+				Subtitles: []Subtitle{
+					{Format: "UTF-8", Language: "English", Forced: true},
+					{Format: "UTF-8", Language: "English"},
+					{Format: "PGS", Language: "Spanish"},
+				},
+			},
+		},
+		{
+			"remux with a DV enhancement layer track and object based audio",
+			remuxDVDump,
+			Report{
+				Container:      "Matroska",
+				Runtime:        2*time.Hour + 16*time.Minute + 18*time.Second,
+				OverallBitrate: 82100,
+				Video: Video{
+					Format: "HEVC", Profile: "Main 10@L5.1@High", Width: 3840, Height: 2160, FrameRate: 23.976, Bitrate: 59600, BitDepth: 10,
+					HDRFormat:      "Dolby Vision, Version 1.0, dvhe.07.06, BL+EL+RPU, HDR10 compatible / SMPTE ST 2086, HDR10 compatible",
+					Transfer:       "PQ",
+					ColorPrimaries: "BT.2020",
+				},
+				Audio: []Audio{
+					{Format: "MLP FBA 16-ch", CommercialName: "Dolby TrueHD with Dolby Atmos", Channels: "7.1", Bitrate: 5581, Language: "English"},
+					{Format: "DTS XLL X", CommercialName: "DTS-HD Master Audio", Channels: "7.1", Bitrate: 4315, Language: "English"},
+					{Format: "AC-3", CommercialName: "Dolby Digital", Channels: "5.1", Bitrate: 640, Language: "Hindi"},
+				},
+				Subtitles: []Subtitle{{Format: "PGS", Language: "English"}},
+			},
+		},
+		{
+			"dvd rip without a channel layout",
+			dvdRipDump,
+			Report{
+				Container:      "AVI",
+				Runtime:        45*time.Minute + 12*time.Second,
+				OverallBitrate: 2165,
+				Video:          Video{Format: "MPEG-4 Visual", Profile: "Advanced Simple@L5", Width: 640, Height: 352, FrameRate: 25, Bitrate: 1986, BitDepth: 8},
+				Audio:          []Audio{{Format: "MPEG Audio", Channels: "2.0", Bitrate: 128, Language: "German"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, ok := Parse(tt.dump)
+			if !ok {
+				t.Fatal("no report found")
+			}
+			if !reflect.DeepEqual(report, tt.want) {
+				t.Errorf("Parse =\n%+v\nwant\n%+v", report, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNotReport(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"Great quality, thanks! Video: HEVC, Audio: English",
+		"General\nFormat : Matroska\nFile size : 2 GiB",
+	} {
+		if _, ok := Parse(text); ok {
+			t.Errorf("Parse(%q) found a report", text)
+		}
+	}
+}
+
+func TestParseNonBreakingSpaces(t *testing.T) {
+	dump := "Video\nWidth  : 1 920 pixels\nHeight : 1 080 pixels\n"
+	report, ok := Parse(dump)
+	if !ok || report.Video.Width != 1920 || report.Video.Height != 1080 {
+		t.Errorf("Parse = %+v %t, want 1920x1080", report.Video, ok)
+	}
+}
+
+func TestAudioChannels(t *testing.T) {
+	tests := []struct {
+		channels string
+		layout   string
+		want     string
+	}{
+		{"6 channels", "L R C LFE Ls Rs", "5.1"},
+		{"8 channels", "L R C LFE Ls Rs Lb Rb", "7.1"},
+		{"2 channels", "L R", "2.0"},
+		{"1 channel", "C", "1.0"},
+		{"6 channels", "", "5.1"},
+		{"2 channels", "", "2.0"},
+		{"Object Based / 8 channels", "Object Based / L R C LFE Ls Rs Lb Rb", "7.1"},
+		{"Object Based / 8 channels", "Object Based", "7.1"},
+		{"8 channels / 6 channels", "C L R Ls Rs Lb Rb LFE / C L R Ls Rs LFE", "7.1"},
+		{"10 channels", "L R C LFE Ls Rs Lb Rb Tfl Tfr", "9.1"},
+		{"Object Based", "Object Based", ""},
+	}
+
+	for _, tt := range tests {
+		var audio Audio
+		audio.parse("channel(s)", tt.channels)
+		audio.parse("channel layout", tt.layout)
+		if audio.Channels != tt.want {
+			t.Errorf("channels %q layout %q = %q, want %q", tt.channels, tt.layout, audio.Channels, tt.want)
+		}
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		want release.Info
+	}{
+		{
+			"web atmos with commentary",
+			webAtmosDump,
+			release.Info{
+				Resolution: "2160P", VideoCodec: "HEVC/x265", BitDepth: "10-bit", Container: "Matroska/MKV", HDR: "HDR10",
+				AudioCodec: "Dolby Atmos", Channels: "5.1",
+				AudioTracks: []release.AudioTrack{
+					{Codec: "Dolby Atmos", Channels: "5.1", Language: "English"},
+					{Codec: "AAC", Channels: "2.0", Language: "English"},
+				},
+				Languages: []string{"English"},
+			},
+		},
+		{
+			"remux with a DV enhancement layer track",
+			remuxDVDump,
+			release.Info{
+				Resolution: "2160P", VideoCodec: "HEVC/x265", BitDepth: "10-bit", Container: "Matroska/MKV",
+				HDR: "HDR10", DolbyVision: true, DVProfile: "7",
+				AudioCodec: "Dolby Atmos", Channels: "7.1",
+				AudioTracks: []release.AudioTrack{
+					{Codec: "Dolby Atmos", Channels: "7.1", Language: "English"},
+					{Codec: "DTS-HD/TrueHD", Channels: "7.1", Language: "English"},
+					{Codec: "AC3", Channels: "5.1", Language: "Hindi"},
+				},
+				Languages: []string{"English", "Hindi"},
+			},
+		},
+		{
+			"dvd rip",
+			dvdRipDump,
+			release.Info{
+				Resolution: "480P", VideoCodec: "XviD", BitDepth: "8-bit",
+				AudioCodec: "MP3", Channels: "2.0",
+				AudioTracks: []release.AudioTrack{{Codec: "MP3", Channels: "2.0", Language: "German"}},
+				Languages:   []string{"German"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, _ := Parse(tt.dump)
+			if info := report.Release(); !reflect.DeepEqual(info, tt.want) {
+				t.Errorf("Release =\n%+v\nwant\n%+v", info, tt.want)
+			}
+		})
+	}
+
+	report, _ := Parse(remuxDVDump)
+	if format := report.Release().HDRFormat(); format != "DV P7+HDR10" {
+		t.Errorf("HDRFormat = %q, want DV P7+HDR10", format)
+	}
+	if languages := report.SubtitleLanguages(); !reflect.DeepEqual(languages, []string{"English"}) {
+		t.Errorf("SubtitleLanguages = %v", languages)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"2 h 16 min", 2*time.Hour + 16*time.Minute},
+		{"45 min 12 s", 45*time.Minute + 12*time.Second},
+		{"1 min 30 s 500 ms", time.Minute + 30*time.Second + 500*time.Millisecond},
+		{"02:16:18.123", 2*time.Hour + 16*time.Minute + 18*time.Second},
+		{"0:45:00", 45 * time.Minute},
+		{"unknown", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseDuration(tt.value); got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"40.0 Mb/s", 40000},
+		{"1 509 kb/s", 1509},
+		{"768 kb/s", 768},
+		{"17.9 Mb/s", 17900},
+		{"Variable", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseBitrate(tt.value); got != tt.want {
+			t.Errorf("parseBitrate(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"3 840 pixels", 3840},
+		{"23.976 (24000/1001) FPS", 23.976},
+		{"10 bits", 10},
+		{"6 channels", 6},
+		{"Object Based", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseNumber(tt.value); got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	tests := []struct {
+		width  int
+		height int
+		want   string
+	}{
+		{3840, 2160, "2160P"},
+		{3840, 1600, "2160P"},
+		{1920, 1080, "1080P"},
+		{1920, 800, "1080P"},
+		{1440, 1080, "1080P"},
+		{1280, 720, "720P"},
+		{1280, 536, "720P"},
+		{720, 576, "480P"},
+		{640, 352, "480P"},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := resolution(tt.width, tt.height); got != tt.want {
+			t.Errorf("resolution(%d, %d) = %q, want %q", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package metainfo reads .torrent files: the info-hash, exact sizes, piece layout and
+// file list a magnet link can't tell before the client fetched the metadata itself.
+package metainfo
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"sanjaix21/krakeneye/internal/bencode"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"sanjaix21/krakeneye/internal/magnet"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxSize is the largest .torrent accepted, even huge season packs stay well below it
+const MaxSize = 10 << 20
+
+var ErrNotTorrent = errors.New("not a .torrent file")
+
+// File is one file of the torrent, Path uses / whatever the uploader's system was
+type File struct {
+	Path   string
+	Length int64
+}
+
+// Torrent is what a .torrent file says
+type Torrent struct {
+	Name         string
+	InfoHash     string // v1, lowercase hex, empty for v2 only torrents
+	InfoHashV2   string // sha2-256 multihash in lowercase hex, only for v2 and hybrid torrents
+	TotalSize    int64
+	PieceLength  int64
+	Pieces       int
+	Private      bool
+	Files        []File
+	Trackers     []string // announce first, then announce-list tier by tier
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time
+}
+
+// Parse reads a .torrent file
+func Parse(data []byte) (*Torrent, error) {
+	decoded, err := bencode.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotTorrent, err)
+	}
+	root, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: top level is no dictionary", ErrNotTorrent)
+	}
+	info, ok := root["info"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: no info dictionary", ErrNotTorrent)
+	}
+
+	// the hash is over the info bytes as they are in the file
+	rawInfo, err := bencode.RawValue(data, "info")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotTorrent, err)
+	}
+
+	t := &Torrent{
+		Name:        stringValue(info, "name"),
+		PieceLength: intValue(info, "piece length"),
+		Private:     intValue(info, "private") == 1,
+		Comment:     stringValue(root, "comment"),
+		CreatedBy:   stringValue(root, "created by"),
+	}
+	if created := intValue(root, "creation date"); created > 0 {
+		t.CreationDate = time.Unix(created, 0)
+	}
+
+	v2 := intValue(info, "meta version") == 2
+	if _, hasPieces := info["pieces"]; hasPieces || !v2 {
+		sum := sha1.Sum(rawInfo)
+		t.InfoHash = hex.EncodeToString(sum[:])
+		t.Pieces = len(stringValue(info, "pieces")) / sha1.Size
+	}
+	if v2 {
+		sum := sha256.Sum256(rawInfo)
+		t.InfoHashV2 = "1220" + hex.EncodeToString(sum[:])
+	}
+
+	switch {
+	case info["length"] != nil:
+		// single file torrent, the name is the file name
+		t.Files = []File{{Path: t.Name, Length: intValue(info, "length")}}
+	case info["files"] != nil:
+		t.Files = v1Files(info["files"])
+	default:
+		t.Files = v2Files(info["file tree"], "")
+	}
+	if len(t.Files) == 0 {
+		return nil, fmt.Errorf("%w: no files", ErrNotTorrent)
+	}
+
+	for _, file := range t.Files {
+		t.TotalSize += file.Length
+	}
+	if t.Pieces == 0 && t.PieceLength > 0 {
+		// v2 only torrents keep their piece hashes per file, count them from the size
+		t.Pieces = int((t.TotalSize + t.PieceLength - 1) / t.PieceLength)
+	}
+
+	t.Trackers = trackers(root)
+	return t, nil
+}
+
+// Load reads a .torrent from disk
+func Load(filePath string) (*Torrent, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return Parse(data)
+}
+
+// Fetch downloads a .torrent and returns it parsed next to its raw bytes
+func Fetch(ctx context.Context, torrentURL string) (*Torrent, []byte, error) {
+	resp, err := httpclient.Default().Get(ctx, torrentURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download %s: %w", torrentURL, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("⚠️ Warning: failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to download %s: status %d", torrentURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download %s: %w", torrentURL, err)
+	}
+	if len(data) > MaxSize {
+		return nil, nil, fmt.Errorf("%w: larger than %d bytes", ErrNotTorrent, MaxSize)
+	}
+
+	t, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, data, nil
+}
+
+// Magnet builds the magnet link of the torrent
+func (t *Torrent) Magnet() string {
+	return magnet.Link{
+		InfoHash:   t.InfoHash,
+		InfoHashV2: t.InfoHashV2,
+		Name:       t.Name,
+		Trackers:   t.Trackers,
+		Length:     t.TotalSize,
+	}.String()
+}
+
+// v1Files reads the files list of a multi file torrent, paths are inside the Name folder
+func v1Files(value any) []File {
+	list, _ := value.([]any)
+
+	var files []File
+	for _, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var parts []string
+		pathList, _ := entry["path"].([]any)
+		for _, part := range pathList {
+			if s, ok := part.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		// BEP 47 padding files only align pieces
+		if attr := stringValue(entry, "attr"); strings.Contains(attr, "p") {
+			continue
+		}
+
+		files = append(files, File{Path: path.Join(parts...), Length: intValue(entry, "length")})
+	}
+	return files
+}
+
+// v2Files walks a BEP 52 file tree, a file is a dictionary with an empty key
+func v2Files(value any, prefix string) []File {
+	tree, _ := value.(map[string]any)
+
+	var files []File
+	for _, name := range slices.Sorted(maps.Keys(tree)) {
+		node, ok := tree[name].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if leaf, ok := node[""].(map[string]any); ok {
+			files = append(files, File{Path: path.Join(prefix, name), Length: intValue(leaf, "length")})
+			continue
+		}
+		files = append(files, v2Files(node, path.Join(prefix, name))...)
+	}
+	return files
+}
+
+// trackers lists announce and announce-list without duplicates
+func trackers(root map[string]any) []string {
+	var list []string
+	add := func(tracker any) {
+		if s, ok := tracker.(string); ok && s != "" && !slices.Contains(list, s) {
+			list = append(list, s)
+		}
+	}
+
+	add(root["announce"])
+	tiers, _ := root["announce-list"].([]any)
+	for _, tier := range tiers {
+		urls, _ := tier.([]any)
+		for _, url := range urls {
+			add(url)
+		}
+	}
+	return list
+}
+
+func stringValue(dict map[string]any, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+func intValue(dict map[string]any, key string) int64 {
+	n, _ := dict[key].(int64)
+	return n
+}
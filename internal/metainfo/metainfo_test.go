@@ -0,0 +1,125 @@
+package metainfo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the info keys are out of order on purpose, the hash must be over the bytes as written
+var v1Info = "d4:name9:movie.mkv6:lengthi1048576e12:piece lengthi262144e6:pieces80:" + strings.Repeat("a", 80) + "e"
+
+var hybridInfo = "d9:file treed8:film.mkvd0:d6:lengthi40000e11:pieces root32:" + strings.Repeat("r", 32) + "eee" +
+	"5:filesld6:lengthi40000e4:pathl8:film.mkveee" +
+	"12:meta versioni2e4:name4:Film12:piece lengthi16384e6:pieces60:" + strings.Repeat("p", 60) + "e"
+
+var v2Info = "d9:file treed1:ad5:b.txtd0:d6:lengthi5eeee5:c.txtd0:d6:lengthi7eeee12:meta versioni2e4:name1:x12:piece lengthi16384ee"
+
+func TestParseV1(t *testing.T) {
+	data := "d8:announce30:udp://tracker.invalid:1337/ann" +
+		"13:announce-listll30:udp://tracker.invalid:1337/annel25:http://backup.invalid/annee" +
+		"7:comment5:hello13:creation datei1700000000e" +
+		"4:info" + v1Info + "e"
+
+	torrent, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if torrent.InfoHash != "2c1cf3cc0554b9f0d55b6f118282751cc4ec1ec2" || torrent.InfoHashV2 != "" {
+		t.Errorf("info-hashes = %q %q", torrent.InfoHash, torrent.InfoHashV2)
+	}
+	if torrent.Name != "movie.mkv" || torrent.TotalSize != 1048576 || torrent.PieceLength != 262144 || torrent.Pieces != 4 {
+		t.Errorf("name/size/piece length/pieces = %q %d %d %d", torrent.Name, torrent.TotalSize, torrent.PieceLength, torrent.Pieces)
+	}
+	if want := []File{{Path: "movie.mkv", Length: 1048576}}; !reflect.DeepEqual(torrent.Files, want) {
+		t.Errorf("files = %+v", torrent.Files)
+	}
+	if want := []string{"udp://tracker.invalid:1337/ann", "http://backup.invalid/ann"}; !reflect.DeepEqual(torrent.Trackers, want) {
+		t.Errorf("trackers = %v, want %v", torrent.Trackers, want)
+	}
+	if torrent.Comment != "hello" || torrent.CreationDate.Unix() != 1700000000 {
+		t.Errorf("comment/date = %q %v", torrent.Comment, torrent.CreationDate)
+	}
+
+	if magnet := torrent.Magnet(); !strings.Contains(magnet, "urn:btih:2c1cf3cc0554b9f0d55b6f118282751cc4ec1ec2") {
+		t.Errorf("magnet = %q", magnet)
+	}
+}
+
+func TestParseV1MultiFile(t *testing.T) {
+	info := "d5:filesl" +
+		"d6:lengthi700e4:pathl3:sub9:movie.mkvee" +
+		"d4:attr1:p6:lengthi324e4:pathl4:.pad3:324ee" +
+		"d6:lengthi24e4:pathl8:info.nfoee" +
+		"e4:name6:Folder12:piece lengthi1024e6:pieces20:" + strings.Repeat("b", 20) + "e"
+
+	torrent, err := Parse([]byte("d4:info" + info + "e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []File{{Path: "sub/movie.mkv", Length: 700}, {Path: "info.nfo", Length: 24}}
+	if !reflect.DeepEqual(torrent.Files, want) {
+		t.Errorf("files = %+v, want %+v without the padding file", torrent.Files, want)
+	}
+	if torrent.TotalSize != 724 {
+		t.Errorf("TotalSize = %d, want 724", torrent.TotalSize)
+	}
+}
+
+func TestParseHybrid(t *testing.T) {
+	torrent, err := Parse([]byte("d4:info" + hybridInfo + "e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if torrent.InfoHash != "01b5b2243d654d5b75e5eae97ca36c4df9893724" {
+		t.Errorf("v1 info-hash = %q", torrent.InfoHash)
+	}
+	if torrent.InfoHashV2 != "122003a3e291636d1b4e6fe80cd7b2080b54de7b5c2a226ef7ee481045f55e56cd7c" {
+		t.Errorf("v2 info-hash = %q", torrent.InfoHashV2)
+	}
+	if torrent.Pieces != 3 || torrent.TotalSize != 40000 {
+		t.Errorf("pieces/size = %d %d", torrent.Pieces, torrent.TotalSize)
+	}
+
+	magnet := torrent.Magnet()
+	if !strings.Contains(magnet, "urn:btih:01b5b2243d654d5b75e5eae97ca36c4df9893724") || !strings.Contains(magnet, "urn:btmh:1220") {
+		t.Errorf("magnet = %q, want both hashes", magnet)
+	}
+}
+
+func TestParseV2Only(t *testing.T) {
+	torrent, err := Parse([]byte("d4:info" + v2Info + "e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if torrent.InfoHash != "" || torrent.InfoHashV2 != "1220008f21cf0675284f47e5b08f57619ae23fc5b25ccb69dc007bea3d9bcab7c4dd" {
+		t.Errorf("info-hashes = %q %q", torrent.InfoHash, torrent.InfoHashV2)
+	}
+	want := []File{{Path: "a/b.txt", Length: 5}, {Path: "c.txt", Length: 7}}
+	if !reflect.DeepEqual(torrent.Files, want) {
+		t.Errorf("files = %+v, want %+v", torrent.Files, want)
+	}
+	if torrent.Pieces != 1 {
+		t.Errorf("Pieces = %d, want 1 counted from the size", torrent.Pieces)
+	}
+}
+
+func TestParseNotTorrent(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"<html>not found</html>",
+		"le",
+		"d7:comment2:hie",
+		"d4:info4:spame",
+		"d4:infod4:name1:aee",
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrNotTorrent) {
+			t.Errorf("Parse(%q) = %v, want ErrNotTorrent", data, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Aggregator searches several sites at the same time and answers like a single site.
+// Every result carries the SiteName it came from, so EnrichTorrents can hand it back to
+// the parser of that site.
+type Aggregator struct {
+	sites   []string
+	parsers map[string]TorrentParser
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{parsers: make(map[string]TorrentParser)}
+}
+
+// Add registers the parser of a site, a second parser for the same site replaces the first
+func (a *Aggregator) Add(siteName string, torrentParser TorrentParser) {
+	if _, ok := a.parsers[siteName]; !ok {
+		a.sites = append(a.sites, siteName)
+	}
+	a.parsers[siteName] = torrentParser
+}
+
+// Sites lists the sites searched, in the order they were added
+func (a *Aggregator) Sites() []string {
+	return slices.Clone(a.sites)
+}
+
+// Parser returns the parser of siteName
+func (a *Aggregator) Parser(siteName string) (TorrentParser, bool) {
+	torrentParser, ok := a.parsers[siteName]
+	return torrentParser, ok
+}
+
+// Search asks every site at once. A site that fails is skipped with a warning, the search
+// only fails when no site returned anything.
+func (a *Aggregator) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	results := make([][]TorrentFile, len(a.sites))
+	errs := make([]error, len(a.sites))
+
+	var wg sync.WaitGroup
+	for i, siteName := range a.sites {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			torrents, err := a.parsers[siteName].Search(ctx, query, opts)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", siteName, err)
+				return
+			}
+			for j := range torrents {
+				torrents[j].SiteName = siteName
+				listingInfoHash(&torrents[j])
+			}
+			results[i] = torrents
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// take turns between sites so MaxResults doesn't fill up from the first one alone
+	var torrents []TorrentFile
+	for row := 0; ; row++ {
+		added := false
+		for _, siteTorrents := range results {
+			if row < len(siteTorrents) {
+				torrents = append(torrents, siteTorrents[row])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	// only listings with magnets (Torznab, KickAss ...) have an info-hash yet, the others
+	// are merged by EnrichTorrents once their detail pages are in
+	torrents = MergeDuplicates(torrents)
+
+	if len(torrents) == 0 {
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoResults
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrNoResults) {
+			fmt.Printf("⚠️  Skipping %v\n", err)
+		}
+	}
+
+	if opts.MaxResults > 0 && len(torrents) > opts.MaxResults {
+		torrents = torrents[:opts.MaxResults]
+	}
+	return torrents, nil
+}
+
+// EnrichTorrents fetches the details of every torrent from the site it was found on, all
+// sites at once, then merges the torrents that turned out to be the same
+func (a *Aggregator) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	enrichedTorrents := make([]TorrentFile, len(torrents))
+	copy(enrichedTorrents, torrents)
+
+	bySite := make(map[string][]int)
+	for i, torrent := range torrents {
+		bySite[torrent.SiteName] = append(bySite[torrent.SiteName], i)
+	}
+
+	// every site reports its own progress, add them up into one
+	var (
+		progressMu sync.Mutex
+		siteDone   = make(map[string]int)
+		done       int
+		wg         sync.WaitGroup
+	)
+	for siteName, indexes := range bySite {
+		torrentParser, ok := a.parsers[siteName]
+		if !ok {
+			continue
+		}
+
+		siteOpts := opts
+		siteOpts.Progress = func(siteProgress int, _ int) {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+
+			done += siteProgress - siteDone[siteName]
+			siteDone[siteName] = siteProgress
+			if opts.Progress != nil {
+				opts.Progress(done, len(torrents))
+			}
+		}
+
+		siteTorrents := make([]TorrentFile, len(indexes))
+		for j, i := range indexes {
+			siteTorrents[j] = torrents[i]
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			enriched := torrentParser.EnrichTorrents(ctx, siteTorrents, siteOpts)
+			for j, i := range indexes {
+				if j < len(enriched) {
+					enrichedTorrents[i] = enriched[j]
+					enrichedTorrents[i].SiteName = siteName
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return MergeDuplicates(enrichedTorrents)
+}
+
+// MergeDuplicates folds torrents with the same info-hash into the first one. It keeps the
+// highest seeder count seen and lists every site in Sources. Torrents without an info-hash
+// are never merged, for most sites that means until their details were fetched.
+func MergeDuplicates(torrents []TorrentFile) []TorrentFile {
+	var merged []TorrentFile
+	seen := make(map[string]int)
+
+	for _, torrent := range torrents {
+		if len(torrent.Sources) == 0 && torrent.SiteName != "" {
+			torrent.Sources = []string{torrent.SiteName}
+		}
+
+		i, ok := seen[torrent.InfoHash]
+		if torrent.InfoHash == "" || !ok {
+			if torrent.InfoHash != "" {
+				seen[torrent.InfoHash] = len(merged)
+			}
+			merged = append(merged, torrent)
+			continue
+		}
+
+		kept := &merged[i]
+		if torrent.Seeders > kept.Seeders {
+			kept.Seeders = torrent.Seeders
+			kept.Leechers = torrent.Leechers
+		}
+		for _, source := range torrent.Sources {
+			if !slices.Contains(kept.Sources, source) {
+				kept.Sources = append(kept.Sources, source)
+			}
+		}
+		// a site without a .torrent download or file list can borrow them
+		if kept.TorrentURL == "" {
+			kept.TorrentURL = torrent.TorrentURL
+		}
+		if len(kept.Files) == 0 && len(torrent.Files) > 0 {
+			kept.Files = torrent.Files
+			kept.Flags = SuspiciousFlags(*kept)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// stubParser returns canned results, enrich fills in what the detail pages would
+type stubParser struct {
+	torrents []TorrentFile
+	err      error
+	enrich   func(torrent *TorrentFile)
+}
+
+func (s *stubParser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return slices.Clone(s.torrents), nil
+}
+
+func (s *stubParser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	enriched := slices.Clone(torrents)
+	for i := range enriched {
+		if s.enrich != nil {
+			s.enrich(&enriched[i])
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(enriched))
+		}
+	}
+	return enriched
+}
+
+const matrixMagnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=The.Matrix.1999.1080p"
+
+func TestMergeDuplicates(t *testing.T) {
+	torrents := []TorrentFile{
+		{Name: "a", SiteName: "1337x", InfoHash: "aaaa", Seeders: 10, Leechers: 4},
+		{Name: "no hash", SiteName: "1337x"},
+		{Name: "a again", SiteName: "rarbg", InfoHash: "aaaa", Seeders: 50, Leechers: 7, TorrentURL: "https://rarbg.invalid/a.torrent"},
+		{Name: "b", SiteName: "rarbg", InfoHash: "bbbb", Seeders: 3},
+		{Name: "no hash either", SiteName: "rarbg"},
+		{Name: "a third time", SiteName: "kickass", InfoHash: "aaaa", Seeders: 20, Leechers: 90, Files: []FileEntry{{Path: "a.mkv"}}},
+	}
+
+	merged := MergeDuplicates(torrents)
+	if len(merged) != 4 {
+		t.Fatalf("got %d torrents, want 4", len(merged))
+	}
+
+	a := merged[0]
+	if a.Name != "a" || a.SiteName != "1337x" {
+		t.Errorf("kept %q from %q, want the first one", a.Name, a.SiteName)
+	}
+	if a.Seeders != 50 || a.Leechers != 7 {
+		t.Errorf("seeders/leechers = %d/%d, want 50/7 from the best seeded copy", a.Seeders, a.Leechers)
+	}
+	if !slices.Equal(a.Sources, []string{"1337x", "rarbg", "kickass"}) {
+		t.Errorf("Sources = %v", a.Sources)
+	}
+	if a.TorrentURL != "https://rarbg.invalid/a.torrent" || len(a.Files) != 1 {
+		t.Errorf("torrent URL %q and %d files, want them borrowed from the duplicates", a.TorrentURL, len(a.Files))
+	}
+
+	if merged[1].Name != "no hash" || merged[3].Name != "no hash either" {
+		t.Errorf("torrents without an info-hash were merged: %q %q", merged[1].Name, merged[3].Name)
+	}
+	if !slices.Equal(merged[2].Sources, []string{"rarbg"}) {
+		t.Errorf("Sources of an unmerged torrent = %v", merged[2].Sources)
+	}
+}
+
+func TestAggregatorSearchMergesListingMagnets(t *testing.T) {
+	aggregator := NewAggregator()
+	aggregator.Add("torznab", &stubParser{torrents: []TorrentFile{
+		{Name: "The.Matrix.1999.1080p", MagnetLink: matrixMagnet, Seeders: 5},
+		{Name: "Other", Seeders: 1},
+	}})
+	aggregator.Add("kickass", &stubParser{torrents: []TorrentFile{
+		{Name: "The Matrix 1999 1080p", MagnetLink: matrixMagnet, Seeders: 40},
+	}})
+
+	torrents, err := aggregator.Search(context.Background(), "the matrix", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 2 {
+		t.Fatalf("got %d torrents, want the shared magnet merged", len(torrents))
+	}
+
+	matrix := torrents[0]
+	if matrix.SiteName != "torznab" || matrix.Seeders != 40 || !slices.Equal(matrix.Sources, []string{"torznab", "kickass"}) {
+		t.Errorf("merged = site %q seeders %d sources %v", matrix.SiteName, matrix.Seeders, matrix.Sources)
+	}
+	if matrix.InfoHash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("InfoHash = %q, want it read from the listing magnet", matrix.InfoHash)
+	}
+}
+
+func TestAggregatorSearchSkipsFailingSite(t *testing.T) {
+	aggregator := NewAggregator()
+	aggregator.Add("1337x", &stubParser{torrents: []TorrentFile{{Name: "a1"}, {Name: "a2"}, {Name: "a3"}}})
+	aggregator.Add("rarbg", &stubParser{err: ErrMirrorUnreachable})
+	aggregator.Add("kickass", &stubParser{torrents: []TorrentFile{{Name: "b1"}}})
+
+	torrents, err := aggregator.Search(context.Background(), "the matrix", SearchOptions{MaxResults: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, torrent := range torrents {
+		names = append(names, torrent.SiteName+"/"+torrent.Name)
+	}
+	// sites take turns, so the limit doesn't fill up from 1337x alone
+	if want := []string{"1337x/a1", "kickass/b1", "1337x/a2"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestAggregatorSearchErrors(t *testing.T) {
+	failing := NewAggregator()
+	failing.Add("1337x", &stubParser{err: ErrBlocked})
+	failing.Add("rarbg", &stubParser{err: ErrLayoutChanged})
+
+	_, err := failing.Search(context.Background(), "the matrix", SearchOptions{})
+	if !errors.Is(err, ErrBlocked) || !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("err = %v, want both sites' errors", err)
+	}
+
+	empty := NewAggregator()
+	empty.Add("1337x", &stubParser{err: ErrNoResults})
+	empty.Add("rarbg", &stubParser{})
+
+	if _, err := empty.Search(context.Background(), "the matrix", SearchOptions{}); !errors.Is(err, ErrNoResults) {
+		t.Errorf("err = %v, want ErrNoResults", err)
+	}
+}
+
+func TestAggregatorEnrichMerges(t *testing.T) {
+	hashes := map[string]string{"a": "aaaa", "b": "aaaa", "c": "cccc"}
+	setHash := func(torrent *TorrentFile) { torrent.InfoHash = hashes[torrent.Name] }
+
+	aggregator := NewAggregator()
+	aggregator.Add("1337x", &stubParser{enrich: setHash})
+	aggregator.Add("rarbg", &stubParser{enrich: setHash})
+
+	torrents := []TorrentFile{
+		{Name: "a", SiteName: "1337x", Seeders: 3},
+		{Name: "b", SiteName: "rarbg", Seeders: 30},
+		{Name: "c", SiteName: "rarbg", Seeders: 1},
+	}
+
+	var last, total int
+	enriched := aggregator.EnrichTorrents(context.Background(), torrents, EnrichOptions{
+		Progress: func(done int, all int) { last, total = done, all },
+	})
+
+	if len(enriched) != 2 {
+		t.Fatalf("got %d torrents, want a and b merged after enrichment", len(enriched))
+	}
+	if enriched[0].Seeders != 30 || !slices.Equal(enriched[0].Sources, []string{"1337x", "rarbg"}) {
+		t.Errorf("merged = seeders %d sources %v", enriched[0].Seeders, enriched[0].Sources)
+	}
+	if last != 3 || total != 3 {
+		t.Errorf("progress ended at %d/%d, want 3/3 summed over the sites", last, total)
+	}
+}
@@ -0,0 +1,331 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"sanjaix21/krakeneye/internal/mediainfo"
+	"sanjaix21/krakeneye/internal/release"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fetchDocument GETs a page and parses it into a goquery document. Failures come back as
+// ErrMirrorUnreachable, ErrBlocked or a *StatusError.
+func fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	return fetchDocumentWith(ctx, httpclient.Default(), pageURL)
+}
+
+func fetchDocumentWith(ctx context.Context, client *httpclient.Client, pageURL string) (*goquery.Document, error) {
+	resp, err := client.Get(ctx, pageURL)
+	if err != nil {
+		return nil, unreachableError(ctx, pageURL, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("⚠️ Warning: failed to close response body: %v", err)
+		}
+	}()
+
+	// challenge pages usually come with a 403/503, look at the body before the status
+	doc, parseErr := goquery.NewDocumentFromReader(resp.Body)
+	if parseErr == nil && IsChallengePage(doc) {
+		return nil, fmt.Errorf("%w: %s", ErrBlocked, pageURL)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: pageURL, StatusCode: resp.StatusCode}
+	}
+
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", parseErr)
+	}
+	// final url after redirects, relative pager links resolve against it
+	doc.Url = resp.Request.URL
+
+	return doc, nil
+}
+
+// listingLayout names the parts of a search results page a parser relies on
+type listingLayout struct {
+	Results   string // container that is there even when nothing matched
+	Rows      string
+	Pager     string // links labelled with page numbers, empty means a single page
+	NoResults string // text the site shows for an empty search, optional
+}
+
+// parseListing runs parseRow over every row. Zero torrents is ErrNoResults when the page
+// looks like an empty search and ErrLayoutChanged when the selectors no longer fit.
+func parseListing(
+	doc *goquery.Document,
+	layout listingLayout,
+	parseRow func(s *goquery.Selection) *TorrentFile,
+) ([]TorrentFile, error) {
+	var torrents []TorrentFile
+
+	rows := doc.Find(layout.Rows)
+	rows.Each(func(i int, s *goquery.Selection) {
+		if torrent := parseRow(s); torrent != nil {
+			torrents = append(torrents, *torrent)
+		}
+	})
+
+	switch {
+	case len(torrents) > 0:
+		return torrents, nil
+
+	case rows.Length() > 0:
+		// rows are there but none of them has a name/link any more
+		return nil, fmt.Errorf("%w: %d rows matched %q but none could be parsed", ErrLayoutChanged, rows.Length(), layout.Rows)
+
+	case layout.NoResults != "" && strings.Contains(doc.Text(), layout.NoResults):
+		return nil, ErrNoResults
+
+	case layout.Results != "" && doc.Find(layout.Results).Length() > 0:
+		return nil, ErrNoResults
+
+	default:
+		return nil, fmt.Errorf("%w: nothing matched %q", ErrLayoutChanged, layout.Rows)
+	}
+}
+
+// paginate walks result pages starting at firstURL, following the layout's pager links.
+// It stops at opts.MaxPages / opts.MaxResults or as soon as a page adds nothing new. Rows
+// labelled with another category than opts.Category are dropped. Only a failing first page
+// is an error, later pages just end the walk, unless ctx was cancelled. An empty result is
+// ErrNoResults.
+func paginate(
+	ctx context.Context,
+	firstURL string,
+	opts SearchOptions,
+	layout listingLayout,
+	parseRow func(s *goquery.Selection) *TorrentFile,
+) ([]TorrentFile, error) {
+	var torrents []TorrentFile
+	seen := make(map[string]bool)
+	pageURL := firstURL
+
+	for page := 1; page <= opts.pages() && pageURL != ""; page++ {
+		if page > 1 {
+			fmt.Println("🌍 Page URL:", pageURL)
+		}
+
+		doc, err := fetchDocument(ctx, pageURL)
+		if err == nil {
+			var pageTorrents []TorrentFile
+			pageTorrents, err = parseListing(doc, layout, parseRow)
+
+			fresh := 0
+			for _, torrent := range pageTorrents {
+				if seen[torrent.Href] {
+					continue
+				}
+				seen[torrent.Href] = true
+				fresh++
+
+				// sites without a category filter still get narrowed down here
+				if opts.matchesCategory(torrent.Category) && !opts.full(len(torrents)) {
+					torrents = append(torrents, torrent)
+				}
+			}
+
+			if err == nil && (fresh == 0 || opts.full(len(torrents))) {
+				break
+			}
+		}
+
+		if err != nil {
+			if page == 1 || ctx.Err() != nil {
+				return nil, err
+			}
+			if !errors.Is(err, ErrNoResults) {
+				log.Printf("⚠️ Warning: stopping at page %d: %v", page, err)
+			}
+			break
+		}
+
+		pageURL = ""
+		if layout.Pager != "" {
+			pageURL = findPageLink(doc, layout.Pager, page)
+		}
+	}
+
+	if len(torrents) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return torrents, nil
+}
+
+// findPageLink looks for a pager link labelled with the next page number and
+// resolves it against the page it was found on
+func findPageLink(doc *goquery.Document, pagerSelector string, page int) string {
+	nextLabel := strconv.Itoa(page + 1)
+
+	var href string
+	doc.Find(pagerSelector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if strings.TrimSpace(s.Text()) != nextLabel {
+			return true
+		}
+		href, _ = s.Attr("href")
+		return false
+	})
+
+	if href == "" {
+		return ""
+	}
+
+	return resolveURL(doc.Url, href)
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return refURL.String()
+	}
+	return base.ResolveReference(refURL).String()
+}
+
+// fillReleaseInfo sets the technical fields from the release name, the description only
+// fills in what the name leaves open. Source is never taken from the description, plot
+// summaries mention "web" and "cam" too often.
+func fillReleaseInfo(torrent *TorrentFile, description string) {
+	info := release.Parse(torrent.Name)
+	details := release.ParseDetails(description)
+	torrent.Release = info
+
+	torrent.Resolution = firstKnown(info.Resolution, details.Resolution)
+	torrent.Source = firstKnown(info.Source)
+	torrent.VideoCodec = firstKnown(info.VideoCodec, details.VideoCodec)
+	torrent.AudioCodec = firstKnown(info.AudioCodec, details.AudioCodec)
+	torrent.Container = firstKnown(info.Container, details.Container)
+
+	// the name usually just says DV, the description may add the profile and fallback layer
+	hdr := info
+	hdr.DolbyVision = info.DolbyVision || details.DolbyVision
+	if hdr.DVProfile == "" {
+		hdr.DVProfile = details.DVProfile
+	}
+	if hdr.HDR == "" {
+		hdr.HDR = details.HDR
+	}
+	torrent.HDRFormat = hdr.HDRFormat()
+
+	// the description usually lists the tracks in more detail than the name
+	audio := info
+	if len(details.AudioTracks) > len(info.AudioTracks) {
+		audio.AudioTracks = details.AudioTracks
+	}
+	audio.Channels = max(info.WidestChannels(), details.WidestChannels())
+	audio.DualAudio = info.DualAudio || details.DualAudio
+	audio.MultiAudio = info.MultiAudio || details.MultiAudio
+	audio.Languages = appendLanguages(info.Languages, details.Languages...)
+	torrent.AudioChannels = audio.WidestChannels()
+	torrent.AudioTracks = audio.TrackCount()
+
+	// the site's language label is not a track of its own, so it doesn't count above
+	torrent.AudioLanguages = appendLanguages([]string{torrent.Language}, audio.Languages...)
+
+	torrent.BitDepth = firstKnown(info.BitDepth, details.BitDepth)
+	if torrent.BitDepth == "Unknown" {
+		// HDR is always at least 10-bit
+		torrent.BitDepth = "8-bit"
+		if torrent.HDRFormat != "" {
+			torrent.BitDepth = "10-bit"
+		}
+	}
+
+	if report, ok := mediainfo.Parse(description); ok {
+		applyMediaInfo(torrent, report)
+	}
+}
+
+// applyMediaInfo overrides the guesses with what MediaInfo measured on the file itself
+func applyMediaInfo(torrent *TorrentFile, report mediainfo.Report) {
+	torrent.MediaInfo = &report
+	media := report.Release()
+
+	override := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	override(&torrent.Resolution, media.Resolution)
+	override(&torrent.VideoCodec, media.VideoCodec)
+	override(&torrent.AudioCodec, media.AudioCodec)
+	override(&torrent.Container, media.Container)
+	override(&torrent.BitDepth, media.BitDepth)
+
+	// short dumps leave the color lines out, only a full one can tell a release is SDR
+	if format := media.HDRFormat(); format != "" || report.Video.Transfer != "" {
+		torrent.HDRFormat = format
+	}
+
+	if len(media.AudioTracks) > 0 {
+		torrent.AudioChannels = media.WidestChannels()
+		torrent.AudioTracks = len(media.AudioTracks)
+		torrent.AudioLanguages = appendLanguages([]string{torrent.Language}, media.Languages...)
+	}
+}
+
+// multilineText is the text of s with <br> turned into line breaks, the way a
+// MediaInfo dump pasted into a description needs it
+func multilineText(s *goquery.Selection) string {
+	s.Find("br").ReplaceWithHtml("\n")
+	return strings.TrimSpace(s.Text())
+}
+
+// appendLanguages adds the normalized languages that aren't in list yet
+func appendLanguages(list []string, languages ...string) []string {
+	var merged []string
+	for _, language := range slices.Concat(list, languages) {
+		language = release.NormalizeLanguage(strings.TrimSpace(language))
+		if language != "" && !slices.Contains(merged, language) {
+			merged = append(merged, language)
+		}
+	}
+	return merged
+}
+
+// firstKnown returns the first non empty value, or "Unknown"
+func firstKnown(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return "Unknown"
+}
+
+// Trusted Uploaders
+
+func isTrustedUploader(uploader string) bool {
+	trustedUploaders := []string{
+		"RARBG", "YTS", "ETRG", "Prof", "PMEDIA", "Wrath", "FGT",
+		"SPARKS", "UTR", "PSA", "DON", "GalaxyRG", "GalaxyTV",
+		"QxR", "Tigole", "CtrlHD", "NTb", "TBS", "RMTeam", "Judas",
+		"SUSPENSE", "EBP", "icecracked", "DataDiva", "Accid",
+		"1DNCreW", "bone111", "NikaNika", "Maxoverpower", "IONICBOII",
+		"Petehollow", "Telly", "mkvCinemas", "TAoE", "prudence25",
+	}
+
+	uploaderLower := strings.ToLower(uploader)
+	for _, trusted := range trustedUploaders {
+		if strings.Contains(uploaderLower, strings.ToLower(trusted)) {
+			return true
+		}
+	}
+
+	return false
+}
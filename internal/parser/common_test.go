@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var testLayout = listingLayout{
+	Results:   "table.results",
+	Rows:      "table.results tr.row",
+	Pager:     "div.pager a",
+	NoResults: "No torrents found",
+}
+
+func testRow(s *goquery.Selection) *TorrentFile {
+	link := s.Find("a.name")
+	href, ok := link.Attr("href")
+	if !ok {
+		return nil
+	}
+	return &TorrentFile{Name: link.Text(), Href: href, Category: s.Find("td.cat").Text()}
+}
+
+// listingPage is a results page with the named rows and pager links up to pages
+func listingPage(rows []string, pages int) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><table class="results">`)
+	for _, row := range rows {
+		name, category, _ := strings.Cut(row, "|")
+		fmt.Fprintf(&b, `<tr class="row"><td><a class="name" href="/torrent/%s/">%s</a></td><td class="cat">%s</td></tr>`, name, name, category)
+	}
+	b.WriteString(`</table><div class="pager">`)
+	for page := 1; page <= pages; page++ {
+		fmt.Fprintf(&b, `<a href="/search/matrix/%d/">%d</a>`, page, page)
+	}
+	b.WriteString(`</div></body></html>`)
+	return b.String()
+}
+
+// listingServer serves /search/matrix/<n>/ from pages, a missing page answers 500
+type listingServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func newListingServer(t *testing.T, pages map[string]string) *listingServer {
+	t.Helper()
+
+	s := &listingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requested = append(s.requested, r.URL.Path)
+		s.mu.Unlock()
+
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *listingServer) paginate(opts SearchOptions) ([]string, error) {
+	torrents, err := paginate(context.Background(), s.URL+"/search/matrix/1/", opts, testLayout, testRow)
+
+	var names []string
+	for _, torrent := range torrents {
+		names = append(names, torrent.Name)
+	}
+	return names, err
+}
+
+func (s *listingServer) pages() int {
+	return len(s.paths())
+}
+
+func (s *listingServer) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requested)
+}
+
+func threePages() map[string]string {
+	return map[string]string{
+		"/search/matrix/1/": listingPage([]string{"a1", "a2"}, 3),
+		"/search/matrix/2/": listingPage([]string{"b1", "b2"}, 3),
+		"/search/matrix/3/": listingPage([]string{"c1", "c2"}, 3),
+	}
+}
+
+func TestPaginateFollowsPager(t *testing.T) {
+	server := newListingServer(t, threePages())
+
+	names, err := server.paginate(SearchOptions{MaxPages: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2", "b1", "b2", "c1", "c2"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	// page 3 has no link to a page 4
+	if server.pages() != 3 {
+		t.Errorf("fetched %v, want 3 pages", server.paths())
+	}
+}
+
+func TestPaginateStopsAtMaxPages(t *testing.T) {
+	server := newListingServer(t, threePages())
+
+	names, err := server.paginate(SearchOptions{MaxPages: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2", "b1", "b2"}; !slices.Equal(names, want) || server.pages() != 2 {
+		t.Errorf("got %v from %v, want %v from 2 pages", names, server.paths(), want)
+	}
+
+	server = newListingServer(t, threePages())
+	if names, _ := server.paginate(SearchOptions{}); !slices.Equal(names, []string{"a1", "a2"}) || server.pages() != 1 {
+		t.Errorf("no MaxPages got %v from %v, want the first page only", names, server.paths())
+	}
+}
+
+func TestPaginateStopsAtMaxResults(t *testing.T) {
+	server := newListingServer(t, threePages())
+
+	names, err := server.paginate(SearchOptions{MaxPages: 3, MaxResults: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2", "b1"}; !slices.Equal(names, want) || server.pages() != 2 {
+		t.Errorf("got %v from %v, want %v from 2 pages", names, server.paths(), want)
+	}
+}
+
+func TestPaginateStopsOnRepeatedPage(t *testing.T) {
+	// some sites answer a page past the end with the first one again
+	pages := threePages()
+	pages["/search/matrix/2/"] = pages["/search/matrix/1/"]
+	server := newListingServer(t, pages)
+
+	names, err := server.paginate(SearchOptions{MaxPages: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2"}; !slices.Equal(names, want) || server.pages() != 2 {
+		t.Errorf("got %v from %v, want %v without page 3", names, server.paths(), want)
+	}
+}
+
+func TestPaginateKeepsEarlierPagesOnError(t *testing.T) {
+	pages := threePages()
+	delete(pages, "/search/matrix/2/")
+	server := newListingServer(t, pages)
+
+	names, err := server.paginate(SearchOptions{MaxPages: 3})
+	if err != nil {
+		t.Fatalf("a failing second page failed the search: %v", err)
+	}
+	if want := []string{"a1", "a2"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// a page whose layout changed ends the walk the same way
+	pages = threePages()
+	pages["/search/matrix/2/"] = `<html><body><div class="new-layout"></div></body></html>`
+	server = newListingServer(t, pages)
+	if names, err := server.paginate(SearchOptions{MaxPages: 3}); err != nil || !slices.Equal(names, []string{"a1", "a2"}) {
+		t.Errorf("got %v %v, want the first page", names, err)
+	}
+}
+
+func TestPaginateFirstPageErrors(t *testing.T) {
+	server := newListingServer(t, map[string]string{})
+	_, err := server.paginate(SearchOptions{MaxPages: 3})
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("err = %v, want the first page's 500", err)
+	}
+
+	server = newListingServer(t, map[string]string{"/search/matrix/1/": `<html><body>No torrents found</body></html>`})
+	if _, err := server.paginate(SearchOptions{MaxPages: 3}); !errors.Is(err, ErrNoResults) {
+		t.Errorf("err = %v, want ErrNoResults", err)
+	}
+}
+
+func TestPaginateFiltersCategory(t *testing.T) {
+	server := newListingServer(t, map[string]string{
+		"/search/matrix/1/": listingPage([]string{"movie|Movies", "show|TV", "unlabelled|"}, 1),
+	})
+
+	names, err := server.paginate(SearchOptions{Category: CategoryMovies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"movie", "unlabelled"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
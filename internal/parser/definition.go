@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// directory the YAML site definitions are read from, KRAKENEYE_DEFINITIONS overrides it
+var DefinitionsDir = definitionsDirFromEnv()
+
+func definitionsDirFromEnv() string {
+	if dir := os.Getenv("KRAKENEYE_DEFINITIONS"); dir != "" {
+		return dir
+	}
+	return "definitions"
+}
+
+// SiteDefinition describes how to scrape a site without writing a Go parser for it
+type SiteDefinition struct {
+	Name    string           `yaml:"name"`
+	Mirrors []string         `yaml:"mirrors"`
+	Search  SearchDefinition `yaml:"search"`
+	Details DetailDefinition `yaml:"details"`
+}
+
+type SearchDefinition struct {
+	// path relative to the mirror, {{query}} is replaced with the escaped query and
+	// {{category}} with the site's value for the requested category (empty for none)
+	Path       string                   `yaml:"path"`
+	Rows       string                   `yaml:"rows"`
+	Results    string                   `yaml:"results"`    // container present even for an empty search, optional
+	NoResults  string                   `yaml:"noresults"`  // text the site shows for an empty search, optional
+	Pager      string                   `yaml:"pager"`      // links labelled with page numbers, optional
+	Categories map[string]string        `yaml:"categories"` // movies/tv/games/music to the site's value
+	Fields     map[string]FieldSelector `yaml:"fields"`
+}
+
+type DetailDefinition struct {
+	Magnet  string                   `yaml:"magnet"`
+	Torrent string                   `yaml:"torrent"` // .torrent download links, optional
+	Fields  map[string]FieldSelector `yaml:"fields"`
+	Files   FileListDefinition       `yaml:"files"` // optional
+}
+
+// FileListDefinition picks the file list off a detail page, path and size are read
+// relative to each row
+type FileListDefinition struct {
+	Rows string        `yaml:"rows"`
+	Path FieldSelector `yaml:"path"`
+	Size FieldSelector `yaml:"size"`
+}
+
+// FieldSelector picks a value out of a row or page, then runs it through the filters in order
+type FieldSelector struct {
+	Selector string   `yaml:"selector"`
+	Attr     string   `yaml:"attr"`
+	Remove   string   `yaml:"remove"` // child elements to drop before reading text
+	Filters  []string `yaml:"filters"`
+
+	filters []fieldFilter // Filters parsed once by LoadDefinition
+}
+
+// fieldFilter is one parsed entry of FieldSelector.Filters
+type fieldFilter struct {
+	name  string
+	re    *regexp.Regexp // regexp
+	sep   string         // split
+	index int            // split
+	from  string         // replace
+	to    string         // replace
+}
+
+// TorrentFile fields a definition can fill
+var definitionFields = map[string]bool{
+	"name": true, "href": true, "magnet": true, "category": true, "date": true, "size": true,
+	"seeders": true, "leechers": true, "downloads": true, "uploader": true, "language": true,
+	"description": true,
+}
+
+var (
+	definitionsMu     sync.Mutex
+	loadedDefinitions = make(map[string][]*SiteDefinition)
+)
+
+// Definitions returns the definitions in dir, read the first time dir is asked for. A dir
+// that can't be read is warned about once and leaves only the built-in parsers.
+func Definitions(dir string) []*SiteDefinition {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+
+	definitions, ok := loadedDefinitions[dir]
+	if !ok {
+		var err error
+		if definitions, err = LoadDefinitions(dir); err != nil {
+			log.Printf("⚠️ Warning: skipping site definitions: %v", err)
+		}
+		loadedDefinitions[dir] = definitions
+	}
+	return definitions
+}
+
+// LoadDefinitions reads every *.yml / *.yaml file in dir, a missing dir is not an error.
+// A file that doesn't load is skipped with a warning, so one typo can't take down the
+// other definitions or the built-in parsers.
+func LoadDefinitions(dir string) ([]*SiteDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read definitions dir: %w", err)
+	}
+
+	var definitions []*SiteDefinition
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		definition, err := LoadDefinition(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("⚠️ Warning: skipping %v", err)
+			continue
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+func LoadDefinition(path string) (*SiteDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definition %s: %w", path, err)
+	}
+
+	var definition SiteDefinition
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse definition %s: %w", path, err)
+	}
+
+	if err := definition.validate(); err != nil {
+		return nil, fmt.Errorf("invalid definition %s: %w", path, err)
+	}
+
+	for name, field := range definition.Search.Fields {
+		if !definitionFields[name] {
+			return nil, fmt.Errorf("invalid definition %s: unknown search field %q", path, name)
+		}
+		if err := field.compile(); err != nil {
+			return nil, fmt.Errorf("invalid definition %s: search field %q: %w", path, name, err)
+		}
+		definition.Search.Fields[name] = field
+	}
+	for name, field := range definition.Details.Fields {
+		if !definitionFields[name] {
+			return nil, fmt.Errorf("invalid definition %s: unknown detail field %q", path, name)
+		}
+		if err := field.compile(); err != nil {
+			return nil, fmt.Errorf("invalid definition %s: detail field %q: %w", path, name, err)
+		}
+		definition.Details.Fields[name] = field
+	}
+
+	if err := definition.Details.Files.Path.compile(); err != nil {
+		return nil, fmt.Errorf("invalid definition %s: file list path: %w", path, err)
+	}
+	if err := definition.Details.Files.Size.compile(); err != nil {
+		return nil, fmt.Errorf("invalid definition %s: file list size: %w", path, err)
+	}
+
+	return &definition, nil
+}
+
+// FindDefinition returns the definition for siteName from dir, or nil if there is none
+func FindDefinition(dir string, siteName string) *SiteDefinition {
+	for _, definition := range Definitions(dir) {
+		if strings.EqualFold(definition.Name, siteName) {
+			return definition
+		}
+	}
+
+	return nil
+}
+
+func (d *SiteDefinition) validate() error {
+	switch {
+	case d.Name == "":
+		return fmt.Errorf("missing name")
+	case !strings.Contains(d.Search.Path, "{{query}}"):
+		return fmt.Errorf("search path must contain {{query}}")
+	case d.Search.Rows == "":
+		return fmt.Errorf("missing search rows selector")
+	}
+
+	if _, ok := d.Search.Fields["name"]; !ok {
+		return fmt.Errorf("missing search field \"name\"")
+	}
+	if _, ok := d.Search.Fields["href"]; !ok {
+		return fmt.Errorf("missing search field \"href\"")
+	}
+
+	return nil
+}
+
+// compile checks the filters and parses them, so rows don't recompile regexps
+func (f *FieldSelector) compile() error {
+	f.filters = nil
+	for _, filter := range f.Filters {
+		name, args, _ := strings.Cut(filter, ":")
+		parsed := fieldFilter{name: name}
+
+		switch name {
+		case "trim", "lower", "upper", "digits":
+		case "regexp":
+			re, err := regexp.Compile(args)
+			if err != nil {
+				return fmt.Errorf("bad regexp filter: %w", err)
+			}
+			parsed.re = re
+		case "split":
+			sep, index, err := splitFilterArgs(args)
+			if err != nil {
+				return err
+			}
+			parsed.sep, parsed.index = sep, index
+		case "replace":
+			oldValue, newValue, ok := strings.Cut(args, ":")
+			if !ok {
+				return fmt.Errorf("replace filter needs old:new")
+			}
+			parsed.from, parsed.to = oldValue, newValue
+		default:
+			return fmt.Errorf("unknown filter %q", name)
+		}
+
+		f.filters = append(f.filters, parsed)
+	}
+
+	return nil
+}
+
+// extract reads the field from s, the empty selector means s itself
+func (f FieldSelector) extract(s *goquery.Selection) string {
+	selection := s
+	if f.Selector != "" {
+		selection = s.Find(f.Selector).First()
+	}
+
+	var value string
+	if f.Attr != "" {
+		value, _ = selection.Attr(f.Attr)
+	} else {
+		if f.Remove != "" {
+			selection = selection.Clone()
+			selection.Find(f.Remove).Remove()
+		}
+		value = selection.Text()
+	}
+
+	return applyFilters(strings.TrimSpace(value), f.filters)
+}
+
+// filters are written as name or name:args
+//
+//	trim, lower, upper, digits (drop everything but 0-9)
+//	regexp:<pattern>      first submatch, or the whole match
+//	split:<sep>:<index>   nth part after splitting on sep
+//	replace:<old>:<new>
+func applyFilters(value string, filters []fieldFilter) string {
+	for _, filter := range filters {
+		switch filter.name {
+		case "trim":
+			value = strings.TrimSpace(value)
+
+		case "lower":
+			value = strings.ToLower(value)
+
+		case "upper":
+			value = strings.ToUpper(value)
+
+		case "digits":
+			value = strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return r
+				}
+				return -1
+			}, value)
+
+		case "regexp":
+			matches := filter.re.FindStringSubmatch(value)
+			switch {
+			case len(matches) > 1:
+				value = matches[1]
+			case len(matches) == 1:
+				value = matches[0]
+			default:
+				value = ""
+			}
+
+		case "split":
+			parts := strings.Split(value, filter.sep)
+			if filter.index < len(parts) {
+				value = strings.TrimSpace(parts[filter.index])
+			} else {
+				value = ""
+			}
+
+		case "replace":
+			value = strings.ReplaceAll(value, filter.from, filter.to)
+		}
+	}
+
+	return value
+}
+
+// separator may itself contain ':', so the index is taken after the last one
+func splitFilterArgs(args string) (string, int, error) {
+	i := strings.LastIndex(args, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("split filter needs sep:index")
+	}
+
+	index, err := strconv.Atoi(args[i+1:])
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("split filter index must be a positive number")
+	}
+
+	return args[:i], index, nil
+}
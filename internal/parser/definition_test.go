@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const rarbgTestDefinition = `name: rarbg-yaml
+mirrors:
+  - https://rarbg.invalid/
+search:
+  path: search/?search={{query}}
+  rows: table.lista2t tr.lista2
+  fields:
+    name:
+      selector: td.lista:nth-child(2) a
+    href:
+      selector: td.lista:nth-child(2) a
+      attr: href
+    category:
+      selector: td.lista:nth-child(3)
+      filters: ["regexp:^(\\w+)/", lower]
+    size:
+      selector: td.lista:nth-child(5)
+    seeders:
+      selector: td.lista:nth-child(6)
+      filters: [digits]
+    leechers:
+      selector: td.lista:nth-child(7)
+      filters: [digits]
+    uploader:
+      selector: td.lista:nth-child(8)
+      filters: ["replace:SWTYBLZ:Sweaty Blaze"]
+details:
+  magnet: a[href^="magnet:"]
+  fields:
+    language:
+      selector: table.lista tr:has(td.header2:contains("Language:")) td.lista
+    downloads:
+      selector: table.lista tr:has(td.header2:contains("Downloads:")) td.lista
+      filters: [digits]
+  files:
+    rows: "#files tr"
+    path:
+      selector: td:nth-child(1)
+    size:
+      selector: td:nth-child(2)
+`
+
+// writeDefinitions puts files (name -> content) in a fresh directory
+func writeDefinitions(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadDefinitionsSkipsBrokenFiles(t *testing.T) {
+	dir := writeDefinitions(t, map[string]string{
+		"rarbg.yml":      rarbgTestDefinition,
+		"broken.yml":     "name: [unclosed",
+		"bad-filter.yml": "name: bad\nsearch:\n  path: s/{{query}}\n  rows: tr\n  fields:\n    name: {filters: [\"regexp:(\"]}\n    href: {}\n",
+		"notes.txt":      "not a definition",
+	})
+
+	definitions, err := LoadDefinitions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 1 || definitions[0].Name != "rarbg-yaml" {
+		t.Fatalf("got %d definitions, want only rarbg-yaml", len(definitions))
+	}
+
+	if FindDefinition(dir, "RARBG-yaml") == nil {
+		t.Error("FindDefinition doesn't find rarbg-yaml")
+	}
+	if FindDefinition(dir, "bad") != nil {
+		t.Error("FindDefinition returned the definition with a broken filter")
+	}
+	if FindDefinition(filepath.Join(dir, "missing"), "rarbg-yaml") != nil {
+		t.Error("a missing definitions dir returned a definition")
+	}
+}
+
+func TestNewParserFallsBackToBuiltin(t *testing.T) {
+	dir := writeDefinitions(t, map[string]string{"1337x.yml": "name: 1337x\nsearch: {rows: tr}\n"})
+
+	previous := DefinitionsDir
+	DefinitionsDir = dir
+	defer func() { DefinitionsDir = previous }()
+
+	torrentParser, err := NewParser("1337x", "https://1337x.invalid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := torrentParser.(*X1337Parser); !ok {
+		t.Errorf("got %T, want the built-in parser in place of the invalid definition", torrentParser)
+	}
+}
+
+func TestGenericParserFixture(t *testing.T) {
+	dir := writeDefinitions(t, map[string]string{"rarbg.yml": rarbgTestDefinition})
+	definition, err := LoadDefinition(filepath.Join(dir, "rarbg.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generic := NewGenericParser("https://rarbg.invalid/", definition)
+	torrents := parseFixtureRows(t, "rarbg_search.html", generic.layout(), generic.ParseTableRow)
+	if len(torrents) != 3 {
+		t.Fatalf("parsed %d rows, want 3", len(torrents))
+	}
+
+	torrent := torrents[0]
+	if torrent.Name != "The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.TrueHD.7.1.Atmos-SWTYBLZ" ||
+		torrent.Href != "/torrent/the-matrix-1999-2160p-uhd-bluray-x265-10bit-hdr-truehd-7-1-atmos-5678901.html" {
+		t.Errorf("name/href = %q %q", torrent.Name, torrent.Href)
+	}
+	if torrent.Category != "movies" || torrent.Size != 21.49 || torrent.Seeders != 312 || torrent.Leechers != 41 {
+		t.Errorf("category/size/seeders/leechers = %q %v %d/%d", torrent.Category, torrent.Size, torrent.Seeders, torrent.Leechers)
+	}
+	if torrent.Uploader != "Sweaty Blaze" {
+		t.Errorf("Uploader = %q, want the replace filter applied", torrent.Uploader)
+	}
+
+	doc, err := loadFixture("rarbg_details.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generic.parseDetails(doc, &torrent)
+
+	if torrent.InfoHash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("InfoHash = %q, MagnetLink = %q", torrent.InfoHash, torrent.MagnetLink)
+	}
+	if torrent.Language != "English" || torrent.Downloads != 4821 {
+		t.Errorf("language/downloads = %q %d", torrent.Language, torrent.Downloads)
+	}
+	if len(torrent.Files) != 2 || torrent.Files[1].Path != "RARBG.txt" {
+		t.Errorf("files = %+v", torrent.Files)
+	}
+}
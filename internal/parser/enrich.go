@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EnrichOptions controls how detail pages are fetched during EnrichTorrents
+type EnrichOptions struct {
+	Concurrency int                       // detail pages fetched at once, below 1 means one at a time
+	Timeout     time.Duration             // per detail page, 0 means no timeout
+	Progress    func(done int, total int) // called after every torrent, never concurrently
+}
+
+func DefaultEnrichOptions() EnrichOptions {
+	return EnrichOptions{
+		Concurrency: 5,
+		Timeout:     15 * time.Second,
+	}
+}
+
+// enrichConcurrently runs fetch for every torrent on a bounded worker pool. The result keeps
+// the input order; torrents that were never fetched (because ctx was cancelled) come back
+// with just their listing data.
+func enrichConcurrently(
+	ctx context.Context,
+	torrents []TorrentFile,
+	opts EnrichOptions,
+	fetch func(ctx context.Context, torrent *TorrentFile) error,
+) []TorrentFile {
+	enrichedTorrents := make([]TorrentFile, len(torrents))
+	copy(enrichedTorrents, torrents)
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(torrents) {
+		workers = len(torrents)
+	}
+
+	var (
+		progressMu sync.Mutex
+		done       int
+		wg         sync.WaitGroup
+	)
+	jobs := make(chan int)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				torrentCopy := torrents[i]
+
+				fetchCtx, fetchCancel := ctx, context.CancelFunc(func() {})
+				if opts.Timeout > 0 {
+					fetchCtx, fetchCancel = context.WithTimeout(ctx, opts.Timeout)
+				}
+				err := fetch(fetchCtx, &torrentCopy)
+				fetchCancel()
+
+				if err != nil && ctx.Err() == nil {
+					fmt.Printf("⚠️  Failed to fetch details for %s: %v\n", torrentCopy.Name, err)
+				}
+				enrichedTorrents[i] = torrentCopy
+
+				progressMu.Lock()
+				done++
+				if opts.Progress != nil {
+					opts.Progress(done, len(torrents))
+				}
+				progressMu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range torrents {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return enrichedTorrents
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// detailServer serves /torrent/<i>/ with the page title "details <i>", the first pages
+// take the longest so they finish last. It counts requests and how many ran at once.
+type detailServer struct {
+	*httptest.Server
+
+	requests    atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func newDetailServer(t *testing.T, n int) *detailServer {
+	t.Helper()
+
+	s := &detailServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		current := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for {
+			highest := s.maxInFlight.Load()
+			if current <= highest || s.maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+
+		i, _ := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/torrent/"), "/"))
+		time.Sleep(time.Duration(n-i) * 2 * time.Millisecond)
+		fmt.Fprintf(w, "<html><head><title>details %d</title></head></html>", i)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// fetch reads the page title into Uploader
+func (s *detailServer) fetch(ctx context.Context, torrent *TorrentFile) error {
+	doc, err := fetchDocument(ctx, s.URL+torrent.Href)
+	if err != nil {
+		return err
+	}
+	torrent.Uploader = doc.Find("title").Text()
+	return nil
+}
+
+func detailTorrents(n int) []TorrentFile {
+	torrents := make([]TorrentFile, n)
+	for i := range torrents {
+		torrents[i] = TorrentFile{Name: fmt.Sprintf("torrent %d", i), Href: fmt.Sprintf("/torrent/%d/", i)}
+	}
+	return torrents
+}
+
+func TestEnrichConcurrentlyKeepsOrder(t *testing.T) {
+	server := newDetailServer(t, 12)
+
+	enriched := enrichConcurrently(context.Background(), detailTorrents(12), EnrichOptions{Concurrency: 4}, server.fetch)
+	if len(enriched) != 12 {
+		t.Fatalf("got %d torrents, want 12", len(enriched))
+	}
+	for i, torrent := range enriched {
+		if torrent.Name != fmt.Sprintf("torrent %d", i) || torrent.Uploader != fmt.Sprintf("details %d", i) {
+			t.Errorf("position %d holds %q with %q", i, torrent.Name, torrent.Uploader)
+		}
+	}
+}
+
+func TestEnrichConcurrentlyLimit(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		server := newDetailServer(t, 12)
+		enrichConcurrently(context.Background(), detailTorrents(12), EnrichOptions{Concurrency: concurrency}, server.fetch)
+
+		if got := server.maxInFlight.Load(); got > int32(concurrency) {
+			t.Errorf("concurrency %d had %d requests in flight", concurrency, got)
+		}
+		if got := server.requests.Load(); got != 12 {
+			t.Errorf("concurrency %d made %d requests, want 12", concurrency, got)
+		}
+	}
+
+	// below 1 is one at a time
+	server := newDetailServer(t, 4)
+	enrichConcurrently(context.Background(), detailTorrents(4), EnrichOptions{}, server.fetch)
+	if got := server.maxInFlight.Load(); got != 1 {
+		t.Errorf("zero concurrency had %d requests in flight, want 1", got)
+	}
+}
+
+func TestEnrichConcurrentlyProgress(t *testing.T) {
+	server := newDetailServer(t, 10)
+
+	var (
+		calls   []int
+		running atomic.Bool
+		overlap atomic.Bool
+	)
+	opts := EnrichOptions{Concurrency: 5, Progress: func(done int, total int) {
+		if !running.CompareAndSwap(false, true) {
+			overlap.Store(true)
+		}
+		defer running.Store(false)
+
+		if total != 10 {
+			t.Errorf("progress total = %d, want 10", total)
+		}
+		calls = append(calls, done)
+	}}
+	enrichConcurrently(context.Background(), detailTorrents(10), opts, server.fetch)
+
+	if overlap.Load() {
+		t.Error("progress was called concurrently")
+	}
+	if len(calls) != 10 {
+		t.Fatalf("progress called %d times, want once per torrent", len(calls))
+	}
+	for i, done := range calls {
+		if done != i+1 {
+			t.Errorf("progress calls = %v, want 1 to 10", calls)
+			break
+		}
+	}
+}
+
+func TestEnrichConcurrentlyCancel(t *testing.T) {
+	server := newDetailServer(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := EnrichOptions{Concurrency: 1, Progress: func(done int, total int) {
+		if done == 3 {
+			cancel()
+		}
+	}}
+	enriched := enrichConcurrently(ctx, detailTorrents(10), opts, server.fetch)
+
+	if len(enriched) != 10 {
+		t.Fatalf("got %d torrents, want all 10 back", len(enriched))
+	}
+	for i, torrent := range enriched {
+		if torrent.Name != fmt.Sprintf("torrent %d", i) {
+			t.Errorf("position %d lost its listing data: %+v", i, torrent)
+		}
+		if fetched := torrent.Uploader != ""; fetched != (i < 3) {
+			t.Errorf("torrent %d fetched = %t, want only the first 3", i, fetched)
+		}
+	}
+	// the job already handed out may still start, with a cancelled ctx it never gets far
+	if got := server.requests.Load(); got > 4 {
+		t.Errorf("%d requests after cancelling at 3", got)
+	}
+}
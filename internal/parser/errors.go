@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// the site answered and really has nothing for the query
+	ErrNoResults = errors.New("no results found")
+	// the mirror could not be reached at all (dns, connect, tls, timeout)
+	ErrMirrorUnreachable = errors.New("mirror unreachable")
+	// the page loaded but the selectors the parser relies on matched nothing
+	ErrLayoutChanged = errors.New("page layout changed, selectors matched nothing")
+	// a cloudflare/ddos-guard style challenge or interstitial page came back instead
+	ErrBlocked = errors.New("blocked by a challenge page")
+	// the mirror answers, but with something else than the site (parked domain, captive portal)
+	ErrNotSite = errors.New("mirror does not serve the site")
+)
+
+// StatusError is a non-OK HTTP status from a mirror
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received non-OK status code %d from %s", e.StatusCode, e.URL)
+}
+
+// IsMirrorError reports whether err means the mirror itself is unusable,
+// so trying another mirror of the same site may help
+func IsMirrorError(err error) bool {
+	var statusErr *StatusError
+	return errors.Is(err, ErrMirrorUnreachable) ||
+		errors.Is(err, ErrBlocked) ||
+		errors.Is(err, ErrLayoutChanged) ||
+		errors.Is(err, ErrNotSite) ||
+		errors.As(err, &statusErr)
+}
+
+// unreachableError wraps a transport error, a cancelled or expired ctx is passed
+// through untouched so callers can tell "gave up" from "mirror is down"
+func unreachableError(ctx context.Context, pageURL string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w: %s: %v", ErrMirrorUnreachable, pageURL, err)
+}
+
+// markers of the common anti-bot interstitials
+var challengeSelectors = []string{
+	"#challenge-form",
+	"#challenge-running",
+	"#cf-challenge-running",
+	".cf-browser-verification",
+	"#cf-wrapper .cf-error-details",
+	`script[src*="/cdn-cgi/challenge-platform/"]`,
+	`form[action*="__cf_chl"]`,
+	"#ddos-guard",
+	`script[src*="ddos-guard"]`,
+}
+
+var challengeTitles = []string{
+	"just a moment",
+	"attention required",
+	"checking your browser",
+	"ddos-guard",
+	"ddos protection",
+	"security check",
+	"access denied",
+}
+
+// IsChallengePage reports whether doc is an anti-bot challenge instead of the real page
+func IsChallengePage(doc *goquery.Document) bool {
+	for _, selector := range challengeSelectors {
+		if doc.Find(selector).Length() > 0 {
+			return true
+		}
+	}
+
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	for _, marker := range challengeTitles {
+		if strings.Contains(title, marker) {
+			return true
+		}
+	}
+
+	return false
+}
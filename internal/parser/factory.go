@@ -5,11 +5,27 @@ import (
 	"strings"
 )
 
+// NewParser returns the parser for siteName. A YAML definition with the same name in
+// DefinitionsDir takes precedence over the built-in parser, so a broken layout can be
+// fixed without rebuilding.
 func NewParser(siteName string, mirrorURL string) (TorrentParser, error) {
+	if definition := FindDefinition(DefinitionsDir, siteName); definition != nil {
+		return NewGenericParser(mirrorURL, definition), nil
+	}
+
 	switch strings.ToLower(siteName) {
 	case "rarbg":
 		return NewRarbgParser(mirrorURL), nil
 
+	case "kickass", "kat":
+		return NewKickassParser(mirrorURL), nil
+
+	case "1337x":
+		return NewX1337Parser(mirrorURL), nil
+
+	case "torznab":
+		return newTorznabParserFromEnv(mirrorURL), nil
+
 	default:
 		return nil, fmt.Errorf("usupported site: %s", siteName)
 	}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MirrorSelector picks the mirrors a FailoverParser moves between
+type MirrorSelector interface {
+	// NextMirror returns a working mirror of siteName that is not in failed
+	NextMirror(siteName string, failed []string) (string, error)
+	// ReportFailure is told about every mirror that failed a request
+	ReportFailure(mirror string, err error)
+}
+
+// detailFetcher is implemented by every built-in parser
+type detailFetcher interface {
+	FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error
+}
+
+// FailoverParser searches one site through whichever of its mirrors works right now. When
+// a search or detail page fails because of the mirror, it asks the selector for another
+// mirror of the same site and repeats the request there. Every result records the mirror
+// that served it.
+type FailoverParser struct {
+	siteName string
+	selector MirrorSelector
+
+	mu     sync.Mutex
+	mirror string
+	parser TorrentParser
+	failed []string
+}
+
+func NewFailoverParser(siteName string, mirror string, selector MirrorSelector) (*FailoverParser, error) {
+	torrentParser, err := NewParser(siteName, mirror)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FailoverParser{
+		siteName: siteName,
+		selector: selector,
+		mirror:   mirror,
+		parser:   torrentParser,
+	}, nil
+}
+
+// Mirror is the mirror requests currently go to
+func (f *FailoverParser) Mirror() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mirror
+}
+
+func (f *FailoverParser) current() (string, TorrentParser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mirror, f.parser
+}
+
+func (f *FailoverParser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	for {
+		mirror, torrentParser := f.current()
+
+		torrents, err := torrentParser.Search(ctx, query, opts)
+		if err == nil {
+			log.Printf("🛰️  %s search served by %s", f.siteName, mirror)
+			for i := range torrents {
+				torrents[i].Mirror = mirror
+			}
+			return torrents, nil
+		}
+
+		if errors.Is(err, ErrNoResults) || !IsMirrorError(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		if failoverErr := f.failover(mirror, err); failoverErr != nil {
+			return nil, errors.Join(err, failoverErr)
+		}
+	}
+}
+
+// FetchTorrentDetails fetches the detail page, moving to another mirror when the current
+// one is down. A missing page is the torrent's problem, not the mirror's, and isn't retried.
+// An absolute Href is moved along with it.
+func (f *FailoverParser) FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error {
+	from := torrent.Mirror
+	for {
+		mirror, torrentParser := f.current()
+		if from != "" && from != mirror {
+			torrent.Href = rebaseHref(torrent.Href, from, mirror)
+		}
+		from = mirror
+
+		fetcher, ok := torrentParser.(detailFetcher)
+		if !ok {
+			return nil
+		}
+
+		err := fetcher.FetchTorrentDetails(ctx, torrent)
+		if err == nil {
+			torrent.Mirror = mirror
+			return nil
+		}
+
+		if !mirrorDown(err) || ctx.Err() != nil {
+			return err
+		}
+		if failoverErr := f.failover(mirror, err); failoverErr != nil {
+			return errors.Join(err, failoverErr)
+		}
+	}
+}
+
+func (f *FailoverParser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	enrichedTorrents := enrichConcurrently(ctx, torrents, opts, f.FetchTorrentDetails)
+
+	served := make(map[string]int)
+	var mirrors []string
+	for _, torrent := range enrichedTorrents {
+		if torrent.Mirror == "" {
+			continue
+		}
+		if served[torrent.Mirror] == 0 {
+			mirrors = append(mirrors, torrent.Mirror)
+		}
+		served[torrent.Mirror]++
+	}
+	for _, mirror := range mirrors {
+		log.Printf("🛰️  %s details of %d torrents served by %s", f.siteName, served[mirror], mirror)
+	}
+
+	return enrichedTorrents
+}
+
+// failover moves away from mirror after err. When another request already moved on it just
+// lets the caller retry on the new mirror.
+func (f *FailoverParser) failover(mirror string, err error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.mirror != mirror {
+		return nil
+	}
+
+	f.selector.ReportFailure(mirror, err)
+	if !slices.Contains(f.failed, mirror) {
+		f.failed = append(f.failed, mirror)
+	}
+
+	next, selectErr := f.selector.NextMirror(f.siteName, f.failed)
+	if selectErr != nil {
+		// every mirror failed once, a later request may try them all again
+		f.failed = nil
+		return selectErr
+	}
+
+	torrentParser, parserErr := NewParser(f.siteName, next)
+	if parserErr != nil {
+		return parserErr
+	}
+
+	log.Printf("🔀 %s: %s failed (%v), switching to %s", f.siteName, mirror, err, next)
+	f.mirror = next
+	f.parser = torrentParser
+	return nil
+}
+
+// rebaseHref moves an absolute href on mirror from to mirror to, hrefs on other hosts and
+// relative ones already follow the parser's mirror
+func rebaseHref(href string, from string, to string) string {
+	hrefURL, err := url.Parse(href)
+	if err != nil || hrefURL.Host == "" {
+		return href
+	}
+	fromURL, fromErr := url.Parse(from)
+	toURL, toErr := url.Parse(to)
+	if fromErr != nil || toErr != nil || !strings.EqualFold(hrefURL.Host, fromURL.Host) {
+		return href
+	}
+
+	hrefURL.Scheme, hrefURL.Host = toURL.Scheme, toURL.Host
+	// a mirror behind a path prefix keeps only the part below it
+	fromPath, toPath := strings.TrimSuffix(fromURL.Path, "/"), strings.TrimSuffix(toURL.Path, "/")
+	if hrefURL.Path == fromPath || strings.HasPrefix(hrefURL.Path, fromPath+"/") {
+		hrefURL.Path = toPath + strings.TrimPrefix(hrefURL.Path, fromPath)
+		hrefURL.RawPath = ""
+	}
+	return hrefURL.String()
+}
+
+// mirrorDown reports whether a failed detail page means the whole mirror is unusable
+func mirrorDown(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return errors.Is(err, ErrMirrorUnreachable) || errors.Is(err, ErrBlocked)
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// listSelector hands out its mirrors in order and remembers what was reported
+type listSelector struct {
+	mirrors []string
+
+	mu       sync.Mutex
+	reported []string
+}
+
+func (s *listSelector) NextMirror(siteName string, failed []string) (string, error) {
+	for _, mirror := range s.mirrors {
+		if !slices.Contains(failed, mirror) {
+			return mirror, nil
+		}
+	}
+	return "", ErrMirrorUnreachable
+}
+
+func (s *listSelector) ReportFailure(mirror string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reported = append(s.reported, mirror)
+}
+
+// fixtureMirror serves the saved 1337x pages, every detail page answers with detailStatus
+func fixtureMirror(t *testing.T, detailStatus int) *httptest.Server {
+	t.Helper()
+
+	search, _ := fixtures.ReadFile("fixtures/1337x_search.html")
+	details, _ := fixtures.ReadFile("fixtures/1337x_details.html")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/torrent/") {
+			w.WriteHeader(detailStatus)
+			if detailStatus == http.StatusOK {
+				_, _ = w.Write(details)
+			}
+			return
+		}
+		_, _ = w.Write(search)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func downMirror(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFailoverSearch(t *testing.T) {
+	down := downMirror(t).URL + "/"
+	up := fixtureMirror(t, http.StatusOK).URL + "/"
+	selector := &listSelector{mirrors: []string{down, up}}
+
+	failover, err := NewFailoverParser("1337x", down, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrents, err := failover.Search(context.Background(), "the matrix", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 3 {
+		t.Fatalf("got %d torrents, want the 3 of the fixture", len(torrents))
+	}
+	for _, torrent := range torrents {
+		if torrent.Mirror != up {
+			t.Errorf("%s served by %q, want %q", torrent.Name, torrent.Mirror, up)
+		}
+	}
+	if failover.Mirror() != up || !slices.Equal(selector.reported, []string{down}) {
+		t.Errorf("mirror %q reported %v, want %q after reporting %q", failover.Mirror(), selector.reported, up, down)
+	}
+}
+
+func TestFailoverDetails(t *testing.T) {
+	down := downMirror(t).URL + "/"
+	up := fixtureMirror(t, http.StatusOK).URL + "/"
+	selector := &listSelector{mirrors: []string{down, up}}
+
+	failover, err := NewFailoverParser("1337x", down, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrents := []TorrentFile{{
+		Name: "The.Matrix.1999.2160p.UHD.BluRay.x265.HDR.TrueHD.Atmos-TERMiNAL",
+		Href: "/torrent/5101234/The-Matrix-1999-2160p-UHD-BluRay-x265-HDR-TrueHD-Atmos/",
+	}}
+	torrents = failover.EnrichTorrents(context.Background(), torrents, EnrichOptions{})
+
+	torrent := torrents[0]
+	if torrent.Mirror != up || torrent.InfoHash != "89abcdef0123456789abcdef0123456789abcdef" {
+		t.Errorf("mirror %q info-hash %q, want the details from %q", torrent.Mirror, torrent.InfoHash, up)
+	}
+	if !slices.Equal(selector.reported, []string{down}) {
+		t.Errorf("reported %v, want %q", selector.reported, down)
+	}
+}
+
+func TestFailoverDetailsAbsoluteHref(t *testing.T) {
+	down := downMirror(t).URL + "/"
+	up := fixtureMirror(t, http.StatusOK).URL + "/"
+	selector := &listSelector{mirrors: []string{down, up}}
+
+	failover, err := NewFailoverParser("1337x", down, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GenericParser and torznab results carry full links to the mirror that listed them
+	path := "torrent/5101234/The-Matrix-1999-2160p-UHD-BluRay-x265-HDR-TrueHD-Atmos/"
+	torrent := TorrentFile{Name: "The.Matrix.1999.2160p.UHD.BluRay.x265.HDR.TrueHD.Atmos-TERMiNAL", Href: down + path, Mirror: down}
+	if err := failover.FetchTorrentDetails(context.Background(), &torrent); err != nil {
+		t.Fatal(err)
+	}
+
+	if torrent.Href != up+path {
+		t.Errorf("Href = %q, want it moved to %q", torrent.Href, up)
+	}
+	if torrent.Mirror != up || torrent.InfoHash != "89abcdef0123456789abcdef0123456789abcdef" {
+		t.Errorf("mirror %q info-hash %q, want the details from %q", torrent.Mirror, torrent.InfoHash, up)
+	}
+}
+
+func TestRebaseHref(t *testing.T) {
+	tests := []struct {
+		href string
+		from string
+		to   string
+		want string
+	}{
+		{"https://a.invalid/torrent/1/x/", "https://a.invalid/", "https://b.invalid/", "https://b.invalid/torrent/1/x/"},
+		{"https://A.invalid/torrent/1/?id=2", "https://a.invalid/", "http://b.invalid:8080/", "http://b.invalid:8080/torrent/1/?id=2"},
+		{"https://a.invalid/proxy/torrent/1/", "https://a.invalid/proxy/", "https://b.invalid/", "https://b.invalid/torrent/1/"},
+		{"https://a.invalid/torrent/1/", "https://a.invalid/", "https://b.invalid/mirror/", "https://b.invalid/mirror/torrent/1/"},
+		{"https://other.invalid/torrent/1/", "https://a.invalid/", "https://b.invalid/", "https://other.invalid/torrent/1/"},
+		{"/torrent/1/", "https://a.invalid/", "https://b.invalid/", "/torrent/1/"},
+	}
+	for _, tt := range tests {
+		if got := rebaseHref(tt.href, tt.from, tt.to); got != tt.want {
+			t.Errorf("rebaseHref(%q, %q, %q) = %q, want %q", tt.href, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFailoverKeepsMirrorOnMissingPage(t *testing.T) {
+	mirror := fixtureMirror(t, http.StatusNotFound).URL + "/"
+	other := fixtureMirror(t, http.StatusOK).URL + "/"
+	selector := &listSelector{mirrors: []string{mirror, other}}
+
+	failover, err := NewFailoverParser("1337x", mirror, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrent := TorrentFile{Href: "/torrent/1/gone/"}
+	err = failover.FetchTorrentDetails(context.Background(), &torrent)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("err = %v, want the 404", err)
+	}
+	if failover.Mirror() != mirror || len(selector.reported) != 0 {
+		t.Errorf("a missing page moved to %q and reported %v", failover.Mirror(), selector.reported)
+	}
+}
+
+func TestFailoverRunsOutOfMirrors(t *testing.T) {
+	down := downMirror(t).URL + "/"
+	selector := &listSelector{mirrors: []string{down}}
+
+	failover, err := NewFailoverParser("1337x", down, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = failover.Search(context.Background(), "the matrix", SearchOptions{})
+	if !errors.Is(err, ErrMirrorUnreachable) {
+		t.Errorf("err = %v, want ErrMirrorUnreachable once every mirror failed", err)
+	}
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"path"
+	"regexp"
+	"sanjaix21/krakeneye/internal/magnet"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FileEntry is one file of a torrent as the detail page lists it
+type FileEntry struct {
+	Path    string
+	Size    float64 // GB, 0 when the page gives none
+	SizeRaw string
+}
+
+// suspicious content flags, a torrent can carry several
+const (
+	FlagExecutable     = "executable"      // .exe, .lnk, .scr ... outside games and software
+	FlagArchive        = "archive"         // .rar, .zip ... where a video file is expected
+	FlagPassword       = "password"        // a "password" file, the archive needs a key bought elsewhere
+	FlagSampleOnly     = "sample-only"     // the only video files are samples
+	FlagSizeMismatch   = "size-mismatch"   // listed files don't add up, or the video is too small for its resolution
+	FlagMagnetMismatch = "magnet-mismatch" // the magnet's name or size belongs to another torrent
+)
+
+var (
+	executableExtensions = []string{".exe", ".msi", ".lnk", ".scr", ".bat", ".cmd", ".com", ".pif", ".vbs", ".js", ".jar", ".ps1", ".apk"}
+	archiveExtensions    = []string{".rar", ".zip", ".7z", ".tar", ".gz", ".cab"}
+	videoExtensions      = []string{".mkv", ".mp4", ".avi", ".m4v", ".ts", ".m2ts", ".wmv", ".mov", ".webm"}
+
+	samplePattern   = regexp.MustCompile(`(?i)(?:^|[^a-z])sample(?:[^a-z]|$)`)
+	splitRarPattern = regexp.MustCompile(`(?i)\.r\d{2}$`)
+
+	// "The.Matrix.mkv (19.8 GB)"
+	sizeSuffixPattern = regexp.MustCompile(`^(.*?)\s*\(([0-9.,]+\s*[KMGT]i?B)\)$`)
+)
+
+// smallest believable main video per resolution, in GB
+var minVideoSize = map[string]float64{
+	"2160P": 1.0,
+	"1080P": 0.25,
+	"720P":  0.1,
+}
+
+// parseFileList reads a file list, split takes one item apart into its path and size text
+func parseFileList(items *goquery.Selection, split func(item *goquery.Selection) (string, string)) []FileEntry {
+	var files []FileEntry
+	items.Each(func(i int, s *goquery.Selection) {
+		filePath, size := split(s)
+		filePath = strings.TrimSpace(filePath)
+		if filePath == "" {
+			return
+		}
+
+		files = append(files, FileEntry{
+			Path:    filePath,
+			Size:    ParseSizeToGB(strings.ReplaceAll(size, ",", "")),
+			SizeRaw: strings.TrimSpace(size),
+		})
+	})
+	return files
+}
+
+// splitSizeSuffix takes the "(1.4 GB)" off the end of a file list item
+func splitSizeSuffix(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if match := sizeSuffixPattern.FindStringSubmatch(text); match != nil {
+		return match[1], match[2]
+	}
+	return text, ""
+}
+
+// SuspiciousFlags looks at the file list for payloads that aren't what the torrent claims
+// to be. A torrent without a file list only gets the checks that need none.
+func SuspiciousFlags(torrent TorrentFile) []string {
+	var flags []string
+	add := func(flag string) {
+		if !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+
+	category := NormalizeCategory(torrent.Category)
+	video := category == CategoryMovies || category == CategoryTV ||
+		(category == CategoryAll && torrent.Release.Resolution != "")
+	software := category == CategoryGames || strings.Contains(strings.ToLower(torrent.Category), "app")
+
+	var total, largestVideo float64
+	var videos, samples, sized int
+	for _, file := range torrent.Files {
+		name := strings.ToLower(path.Base(strings.ReplaceAll(file.Path, "\\", "/")))
+		ext := path.Ext(name)
+		// "31 B" parses to 0 GB but is still a known size
+		if file.SizeRaw != "" {
+			total += file.Size
+			sized++
+		}
+
+		switch {
+		case ext == ".lnk" || (slices.Contains(executableExtensions, ext) && !software):
+			// a shortcut has no business in any download
+			add(FlagExecutable)
+
+		case video && (slices.Contains(archiveExtensions, ext) || splitRarPattern.MatchString(name)):
+			add(FlagArchive)
+
+		case slices.Contains(videoExtensions, ext):
+			if samplePattern.MatchString(file.Path) {
+				samples++
+			} else {
+				videos++
+				largestVideo = max(largestVideo, file.Size)
+			}
+		}
+
+		if strings.Contains(name, "password") {
+			add(FlagPassword)
+		}
+	}
+
+	if video && samples > 0 && videos == 0 {
+		add(FlagSampleOnly)
+	}
+
+	// the site rounds the total, anything past 10% is more than rounding
+	if sized > 0 && sized == len(torrent.Files) && torrent.Size > 0 && (total > torrent.Size*1.1 || total < torrent.Size*0.9) {
+		add(FlagSizeMismatch)
+	}
+	if minimum, ok := minVideoSize[torrent.Resolution]; ok && video {
+		mainVideo := largestVideo
+		if len(torrent.Files) == 0 {
+			mainVideo = torrent.Size
+		}
+		if mainVideo > 0 && mainVideo < minimum {
+			add(FlagSizeMismatch)
+		}
+	}
+
+	if link, err := magnet.Parse(torrent.MagnetLink); err == nil && !magnetMatches(link, torrent) {
+		add(FlagMagnetMismatch)
+	}
+
+	return flags
+}
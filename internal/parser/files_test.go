@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSuspiciousFlags(t *testing.T) {
+	movie := func(files ...FileEntry) TorrentFile {
+		return TorrentFile{Name: "The.Matrix.1999.1080p.BluRay.x264-GRP", Category: "Movies", Resolution: "1080P", Size: 2.0, Files: files}
+	}
+	file := func(path string, size float64) FileEntry {
+		return FileEntry{Path: path, Size: size, SizeRaw: "x"}
+	}
+	mkv := file("The.Matrix.1999.1080p.BluRay.x264-GRP/The.Matrix.1999.1080p.BluRay.x264-GRP.mkv", 1.98)
+	nfo := file("The.Matrix.1999.1080p.BluRay.x264-GRP/grp.nfo", 0)
+
+	withCategory := func(torrent TorrentFile, category string) TorrentFile {
+		torrent.Category = category
+		return torrent
+	}
+
+	tests := []struct {
+		name    string
+		torrent TorrentFile
+		want    []string
+	}{
+		{"clean release", movie(mkv, nfo, file("Subs/English.srt", 0.01)), nil},
+		{"no file list", movie(), nil},
+
+		{"executable next to the video", movie(mkv, file("Codec/Install.exe", 0.01)), []string{FlagExecutable}},
+		{"windows path", movie(mkv, file(`Codec\Setup.MSI`, 0.01)), []string{FlagExecutable}},
+		{"screensaver", movie(mkv, file("The.Matrix.1999.1080p.scr", 0.01)), []string{FlagExecutable}},
+		{"shortcut", movie(mkv, file("Watch in HD.lnk", 0)), []string{FlagExecutable}},
+		{"game installer", withCategory(movie(file("setup.exe", 1.99)), "Games"), nil},
+		{"shortcut in a game", withCategory(movie(file("setup.exe", 1.5), file("Play.lnk", 0.49)), "Games"), []string{FlagExecutable}},
+		{"software", withCategory(movie(file("app.msi", 2)), "Apps"), nil},
+
+		{"rar instead of a video", movie(file("movie.rar", 1.0), file("movie.r00", 0.98)), []string{FlagArchive}},
+		{"zip next to the video", movie(mkv, file("extras.zip", 0.01)), []string{FlagArchive}},
+		{"archive in a game", withCategory(movie(file("game.7z", 2)), "Games"), nil},
+
+		{"password file", movie(mkv, file("Password.txt", 0)), []string{FlagPassword}},
+		{"readme for password", movie(mkv, file("READ ME FOR PASSWORD.url", 0)), []string{FlagPassword}},
+		{
+			"password protected archive",
+			movie(file("The.Matrix.rar", 1.98), file("password.txt", 0), file("Play Now.exe", 0.01)),
+			[]string{FlagArchive, FlagPassword, FlagExecutable},
+		},
+
+		{"sample only", TorrentFile{Category: "Movies", Resolution: "1080P", Size: 0.05, Files: []FileEntry{file("Sample/the.matrix.sample.mkv", 0.05)}}, []string{FlagSampleOnly}},
+		{"sample next to the video", movie(mkv, file("Sample/sample-grp.mkv", 0.02)), nil},
+		{"word with sample in it", movie(file("Samplers.Story.mkv", 2.0)), nil},
+
+		{"files don't add up", movie(file("The.Matrix.mkv", 1.0)), []string{FlagSizeMismatch}},
+		{"rounded total", movie(file("The.Matrix.mkv", 1.85)), nil},
+		{"files without sizes", movie(FileEntry{Path: "The.Matrix.mkv"}), nil},
+		{"2160p video too small", TorrentFile{Category: "Movies", Resolution: "2160P", Size: 0.5, Files: []FileEntry{file("The.Matrix.2160p.mkv", 0.5)}}, []string{FlagSizeMismatch}},
+		{"1080p listing too small", TorrentFile{Category: "Movies", Resolution: "1080P", Size: 0.1}, []string{FlagSizeMismatch}},
+		{"small 720p tv episode", TorrentFile{Category: "TV", Resolution: "720P", Size: 0.2}, nil},
+
+		{
+			"magnet of another torrent",
+			TorrentFile{Name: "The.Matrix.1999.1080p.BluRay.x264-GRP", Category: "Movies", Size: 2,
+				MagnetLink: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=Totally.Different.Movie.2020"},
+			[]string{FlagMagnetMismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuspiciousFlags(tt.torrent); !slices.Equal(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// GenericParser scrapes any site described by a YAML SiteDefinition
+type GenericParser struct {
+	BaseURL    string
+	Definition *SiteDefinition
+}
+
+func NewGenericParser(mirrorURL string, definition *SiteDefinition) *GenericParser {
+	return &GenericParser{
+		BaseURL:    mirrorURL,
+		Definition: definition,
+	}
+}
+
+func (g *GenericParser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	searchURL := g.searchURL(query, opts)
+	fmt.Println("🌍 Search URL:", searchURL)
+
+	torrents, err := paginate(ctx, searchURL, opts, g.layout(), g.ParseTableRow)
+	if err != nil {
+		return nil, fmt.Errorf("%s search failed: %w", g.Definition.Name, err)
+	}
+
+	fmt.Printf("📦 Found %d torrents\n", len(torrents))
+	return torrents, nil
+}
+
+// searchURL is the first results page for query
+func (g *GenericParser) searchURL(query string, opts SearchOptions) string {
+	searchPath := strings.ReplaceAll(g.Definition.Search.Path, "{{query}}", url.QueryEscape(query))
+	searchPath = strings.ReplaceAll(searchPath, "{{category}}", g.Definition.Search.Categories[opts.Category])
+	return g.absoluteURL(searchPath)
+}
+
+// layout builds the listingLayout from the definition, pages are only followed with a pager selector
+func (g *GenericParser) layout() listingLayout {
+	search := g.Definition.Search
+	return listingLayout{
+		Results:   search.Results,
+		Rows:      search.Rows,
+		Pager:     search.Pager,
+		NoResults: search.NoResults,
+	}
+}
+
+func (g *GenericParser) ParseTableRow(s *goquery.Selection) *TorrentFile {
+	fields := g.Definition.Search.Fields
+
+	href := fields["href"].extract(s)
+	name := fields["name"].extract(s)
+	if href == "" || name == "" {
+		return nil // skip invalid rows
+	}
+
+	torrent := &TorrentFile{
+		Name: name,
+		Href: href,
+	}
+
+	for field, selector := range fields {
+		g.setField(torrent, field, selector.extract(s))
+	}
+
+	return torrent
+}
+
+func (g *GenericParser) FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error {
+	doc, err := fetchDocument(ctx, g.detailURL(*torrent))
+	if err != nil {
+		return fmt.Errorf("failed to fetch torrent details: %w", err)
+	}
+
+	g.parseDetails(doc, torrent)
+	return nil
+}
+
+func (g *GenericParser) detailURL(torrent TorrentFile) string {
+	return g.absoluteURL(torrent.Href)
+}
+
+// parseDetails fills torrent from its detail page
+func (g *GenericParser) parseDetails(doc *goquery.Document, torrent *TorrentFile) {
+	details := g.Definition.Details
+	if details.Magnet != "" {
+		if magnet := pickMagnet(doc.Find(details.Magnet), *torrent); magnet != "" {
+			torrent.MagnetLink = magnet
+		}
+	}
+	if details.Torrent != "" {
+		torrent.TorrentURL = findTorrentURL(doc, details.Torrent)
+	}
+
+	for field, selector := range details.Fields {
+		g.setField(torrent, field, selector.extract(doc.Selection))
+	}
+
+	if files := details.Files; files.Rows != "" {
+		torrent.Files = parseFileList(doc.Find(files.Rows), func(row *goquery.Selection) (string, string) {
+			return files.Path.extract(row), files.Size.extract(row)
+		})
+	}
+
+	fillReleaseInfo(torrent, torrent.MetaInfo)
+
+	torrent.Trusted = isTrustedUploader(torrent.Uploader)
+	verifyMagnet(torrent)
+	torrent.Flags = SuspiciousFlags(*torrent)
+}
+
+func (g *GenericParser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	return enrichConcurrently(ctx, torrents, opts, g.FetchTorrentDetails)
+}
+
+// setField maps a definition field name onto the TorrentFile, empty values never overwrite
+func (g *GenericParser) setField(torrent *TorrentFile, field string, value string) {
+	if value == "" {
+		return
+	}
+
+	switch field {
+	case "name":
+		torrent.Name = value
+	case "href":
+		torrent.Href = value
+	case "magnet":
+		torrent.MagnetLink = value
+	case "category":
+		torrent.Category = value
+	case "date":
+		torrent.UploadDate = value
+	case "size":
+		torrent.SizeRaw = value
+		torrent.Size = ParseSizeToGB(value)
+	case "seeders":
+		if seeders, err := strconv.Atoi(value); err == nil {
+			torrent.Seeders = seeders
+		}
+	case "leechers":
+		if leechers, err := strconv.Atoi(value); err == nil {
+			torrent.Leechers = leechers
+		}
+	case "downloads":
+		if downloads, err := strconv.Atoi(value); err == nil {
+			torrent.Downloads = downloads
+		}
+	case "uploader":
+		torrent.Uploader = value
+	case "language":
+		torrent.Language = value
+	case "description":
+		torrent.MetaInfo = value
+	}
+}
+
+func (g *GenericParser) absoluteURL(ref string) string {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		return ref
+	}
+	return strings.TrimSuffix(g.BaseURL, "/") + "/" + strings.TrimPrefix(ref, "/")
+}
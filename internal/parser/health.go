@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"sanjaix21/krakeneye/internal/magnet"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// scraper is what the HTML parsers expose on top of TorrentParser so a health check can
+// look at their pages step by step
+type scraper interface {
+	searchURL(query string, opts SearchOptions) string
+	layout() listingLayout
+	ParseTableRow(s *goquery.Selection) *TorrentFile
+	detailURL(torrent TorrentFile) string
+	parseDetails(doc *goquery.Document, torrent *TorrentFile)
+}
+
+// HealthCheck is one expectation a parser has of a site's markup
+type HealthCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// HealthReport is the diagnosis of one parser against fixture pages or a live mirror
+type HealthReport struct {
+	Site    string
+	Source  string // "fixture" or the mirror that was checked
+	Healthy bool
+	Checks  []HealthCheck
+}
+
+func newHealthReport(site string, source string) HealthReport {
+	return HealthReport{Site: site, Source: source, Healthy: true}
+}
+
+func (r *HealthReport) add(name string, ok bool, format string, args ...any) {
+	r.Checks = append(r.Checks, HealthCheck{
+		Name:   name,
+		OK:     ok,
+		Detail: fmt.Sprintf(format, args...),
+	})
+	if !ok {
+		r.Healthy = false
+	}
+}
+
+// saved search and detail pages of the built-in parsers, named <site>_search.html and <site>_details.html
+//
+//go:embed fixtures/*.html
+var fixtures embed.FS
+
+// DefaultHealthQuery is searched by live checks, popular enough to have results everywhere
+const DefaultHealthQuery = "the matrix"
+
+// FixtureSites are the built-in parsers that ship with fixture pages
+var FixtureSites = []string{"rarbg", "1337x", "kickass"}
+
+// builtinScraper skips NewParser on purpose, a YAML definition must not replace
+// the parser whose fixtures are being checked
+func builtinScraper(siteName string, mirrorURL string) scraper {
+	switch siteName {
+	case "rarbg":
+		return NewRarbgParser(mirrorURL)
+	case "1337x":
+		return NewX1337Parser(mirrorURL)
+	case "kickass":
+		return NewKickassParser(mirrorURL)
+	default:
+		return nil
+	}
+}
+
+// CheckFixtures runs the health checks of every built-in parser against its saved pages.
+// It needs no network, so it catches selectors broken by a code change, not by the site.
+func CheckFixtures() []HealthReport {
+	var reports []HealthReport
+	for _, siteName := range FixtureSites {
+		reports = append(reports, checkFixture(siteName))
+	}
+	return reports
+}
+
+func checkFixture(siteName string) HealthReport {
+	report := newHealthReport(siteName, "fixture")
+	s := builtinScraper(siteName, "https://fixture.invalid/")
+
+	listing, err := loadFixture(siteName + "_search.html")
+	if err != nil {
+		report.add("search page", false, "%v", err)
+		return report
+	}
+	torrents := checkListing(&report, listing, s.layout(), s.ParseTableRow)
+	if len(torrents) == 0 {
+		return report
+	}
+
+	details, err := loadFixture(siteName + "_details.html")
+	if err != nil {
+		report.add("detail page", false, "%v", err)
+		return report
+	}
+	checkDetails(&report, details, s, torrents[0])
+
+	return report
+}
+
+func loadFixture(name string) (*goquery.Document, error) {
+	body, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("missing fixture %s: %w", name, err)
+	}
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
+}
+
+// CheckLive searches a mirror for query and checks the results page and the first
+// detail page the same way CheckFixtures does. Torznab has no markup, for it the check
+// is just whether a search goes through.
+func CheckLive(ctx context.Context, siteName string, mirrorURL string, query string) HealthReport {
+	report := newHealthReport(siteName, mirrorURL)
+
+	torrentParser, err := NewParser(siteName, mirrorURL)
+	if err != nil {
+		report.add("parser", false, "%v", err)
+		return report
+	}
+
+	s, ok := torrentParser.(scraper)
+	if !ok {
+		torrents, err := torrentParser.Search(ctx, query, SearchOptions{MaxPages: 1, MaxResults: 1})
+		if err != nil && !errors.Is(err, ErrNoResults) {
+			report.add("search", false, "%v", err)
+			return report
+		}
+		report.add("search", true, "%d results for %q", len(torrents), query)
+		return report
+	}
+
+	searchURL := s.searchURL(query, DefaultSearchOptions())
+	listing, err := fetchDocument(ctx, searchURL)
+	if err != nil {
+		report.add("search page", false, "%v", err)
+		return report
+	}
+	report.add("search page", true, "fetched %s", searchURL)
+
+	layout := s.layout()
+	torrents := checkListing(&report, listing, layout, s.ParseTableRow)
+	if len(torrents) == 0 {
+		if layout.NoResults != "" && strings.Contains(listing.Text(), layout.NoResults) {
+			report.add("query", false, "the site has no results for %q, try another query", query)
+		}
+		return report
+	}
+
+	details, err := fetchDocument(ctx, s.detailURL(torrents[0]))
+	if err != nil {
+		report.add("detail page", false, "%v", err)
+		return report
+	}
+	checkDetails(&report, details, s, torrents[0])
+
+	return report
+}
+
+// checkListing checks that the layout selectors still match and that rows yield names,
+// sizes and seeders. It returns the rows that parsed.
+func checkListing(
+	report *HealthReport,
+	doc *goquery.Document,
+	layout listingLayout,
+	parseRow func(s *goquery.Selection) *TorrentFile,
+) []TorrentFile {
+	if IsChallengePage(doc) {
+		report.add("challenge page", false, "got an anti-bot challenge instead of results")
+		return nil
+	}
+
+	if layout.Results != "" {
+		found := doc.Find(layout.Results).Length()
+		report.add("results container", found > 0, "%q matched %d elements", layout.Results, found)
+	}
+
+	rows := doc.Find(layout.Rows)
+	report.add("rows", rows.Length() > 0, "%q matched %d rows", layout.Rows, rows.Length())
+	if rows.Length() == 0 {
+		return nil
+	}
+
+	var torrents []TorrentFile
+	var sized, seeded int
+	rows.Each(func(i int, s *goquery.Selection) {
+		torrent := parseRow(s)
+		if torrent == nil || torrent.Name == "" {
+			return
+		}
+		torrents = append(torrents, *torrent)
+
+		if torrent.Size > 0 {
+			sized++
+		}
+		if torrent.Seeders > 0 {
+			seeded++
+		}
+	})
+
+	report.add("names", len(torrents) > 0, "%d/%d rows have a name and link", len(torrents), rows.Length())
+	if len(torrents) == 0 {
+		return nil
+	}
+	// a torrent may have no seeders, but a whole page of them means the column moved
+	report.add("sizes", sized == len(torrents), "%d/%d rows have a size", sized, len(torrents))
+	report.add("seeders", seeded > 0, "%d/%d rows have seeders", seeded, len(torrents))
+
+	return torrents
+}
+
+// checkDetails checks that the detail page still carries a magnet link
+func checkDetails(report *HealthReport, doc *goquery.Document, s scraper, torrent TorrentFile) {
+	if IsChallengePage(doc) {
+		report.add("challenge page", false, "got an anti-bot challenge instead of the detail page")
+		return
+	}
+
+	// the listing magnet some sites carry must not hide a broken detail page
+	torrent.MagnetLink = ""
+	s.parseDetails(doc, &torrent)
+
+	_, err := magnet.Parse(torrent.MagnetLink)
+	report.add("magnet link", err == nil, "detail page of %q", torrent.Name)
+}
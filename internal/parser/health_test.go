@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// a parked domain: it answers 200 but matches none of the parsers' selectors
+const parkedPage = `<!DOCTYPE html>
+<html><head><title>This domain is for sale</title></head>
+<body><div class="parked"><h1>1337x.to</h1><p>Buy this domain today!</p></div></body>
+</html>`
+
+func TestCheckFixtures(t *testing.T) {
+	reports := CheckFixtures()
+	if len(reports) != len(FixtureSites) {
+		t.Fatalf("got %d reports, want one per fixture site", len(reports))
+	}
+
+	for _, report := range reports {
+		if !report.Healthy {
+			t.Errorf("%s is unhealthy against its fixtures:", report.Site)
+		}
+		for _, check := range report.Checks {
+			if !check.OK {
+				t.Errorf("  %s: %s", check.Name, check.Detail)
+			}
+		}
+	}
+}
+
+func TestCheckListingNoMatch(t *testing.T) {
+	for _, siteName := range FixtureSites {
+		t.Run(siteName, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(parkedPage))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := builtinScraper(siteName, "https://fixture.invalid/")
+			report := newHealthReport(siteName, "test")
+			torrents := checkListing(&report, doc, s.layout(), s.ParseTableRow)
+
+			if report.Healthy || len(torrents) != 0 {
+				t.Errorf("a parked page passed: healthy %t, %d torrents", report.Healthy, len(torrents))
+			}
+		})
+	}
+}
+
+func TestCheckLive(t *testing.T) {
+	search, _ := fixtures.ReadFile("fixtures/1337x_search.html")
+	details, _ := fixtures.ReadFile("fixtures/1337x_details.html")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/torrent/") {
+			_, _ = w.Write(details)
+			return
+		}
+		_, _ = w.Write(search)
+	}))
+	defer server.Close()
+
+	report := CheckLive(context.Background(), "1337x", server.URL+"/", DefaultHealthQuery)
+	if !report.Healthy {
+		t.Errorf("live check of a mirror serving the fixtures failed: %+v", report.Checks)
+	}
+}
+
+func TestCheckLiveParked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(parkedPage))
+	}))
+	defer server.Close()
+
+	report := CheckLive(context.Background(), "1337x", server.URL+"/", DefaultHealthQuery)
+	if report.Healthy {
+		t.Errorf("live check of a parked domain passed: %+v", report.Checks)
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type KickassParser struct {
+	BaseURL string
+}
+
+// search categories to KAT's category: keyword
+var kickassCategories = map[string]string{
+	CategoryMovies: "movies",
+	CategoryTV:     "tv",
+	CategoryGames:  "games",
+	CategoryMusic:  "music",
+}
+
+func NewKickassParser(mirrorURL string) *KickassParser {
+	return &KickassParser{
+		BaseURL: mirrorURL,
+	}
+}
+
+// kickassLayout is what KickassParser expects of a search results page
+var kickassLayout = listingLayout{
+	Results:   "table.data",
+	Rows:      "table.data tr.odd, table.data tr.even",
+	Pager:     "div.pages a",
+	NoResults: "Nothing found!",
+}
+
+func (k *KickassParser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	searchURL := k.searchURL(query, opts)
+	fmt.Println("🌍 Search URL:", searchURL)
+
+	torrents, err := paginate(ctx, searchURL, opts, k.layout(), k.ParseTableRow)
+	if err != nil {
+		return nil, fmt.Errorf("KAT search failed: %w", err)
+	}
+
+	fmt.Printf("📦 Found %d torrents\n", len(torrents))
+	return torrents, nil
+}
+
+// searchURL is the first results page for query
+func (k *KickassParser) searchURL(query string, opts SearchOptions) string {
+	// KAT filters on a category: keyword inside the query itself
+	if category, ok := kickassCategories[opts.Category]; ok {
+		query += " category:" + category
+	}
+	return fmt.Sprintf("%susearch/%s/", k.BaseURL, url.PathEscape(query))
+}
+
+func (k *KickassParser) layout() listingLayout {
+	return kickassLayout
+}
+
+func (k *KickassParser) ParseTableRow(s *goquery.Selection) *TorrentFile {
+	cells := s.Children()
+	linkElement := cells.Eq(0).Find("a.cellMainLink").First()
+
+	href, exists := linkElement.Attr("href")
+	if !exists {
+		return nil // skip invalid rows
+	}
+
+	torrent := &TorrentFile{
+		Name: strings.TrimSpace(linkElement.Text()),
+		Href: href,
+	}
+
+	// some mirrors put the magnet straight on the listing
+	if magnet, ok := cells.Eq(0).Find(`a[href^="magnet:"]`).Attr("href"); ok {
+		torrent.MagnetLink = magnet
+	}
+
+	// Category, "Posted by X in Movies > HD"
+	if category := strings.TrimSpace(cells.Eq(0).Find(`span[id^="cat_"] a`).First().Text()); category != "" {
+		torrent.Category = category
+	}
+
+	// Size
+	if sizeText := strings.TrimSpace(cells.Eq(1).Text()); sizeText != "" {
+		torrent.SizeRaw = sizeText
+		torrent.Size = ParseSizeToGB(sizeText)
+	}
+
+	// Uploader
+	torrent.Uploader = strings.TrimSpace(cells.Eq(2).Text())
+	if torrent.Uploader == "" {
+		torrent.Uploader = strings.TrimSpace(cells.Eq(0).Find(`a[href^="/user/"]`).First().Text())
+	}
+
+	// UploadDate
+	torrent.UploadDate = strings.TrimSpace(cells.Eq(3).Text())
+
+	// Seeders
+	if seeders, err := strconv.Atoi(strings.TrimSpace(cells.Eq(4).Text())); err == nil {
+		torrent.Seeders = seeders
+	}
+
+	// Leechers
+	if leechers, err := strconv.Atoi(strings.TrimSpace(cells.Eq(5).Text())); err == nil {
+		torrent.Leechers = leechers
+	}
+
+	return torrent
+}
+
+func (k *KickassParser) FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error {
+	doc, err := fetchDocument(ctx, k.detailURL(*torrent))
+	if err != nil {
+		return fmt.Errorf("failed to fetch torrent details: %w", err)
+	}
+
+	k.parseDetails(doc, torrent)
+	return nil
+}
+
+func (k *KickassParser) detailURL(torrent TorrentFile) string {
+	if strings.HasPrefix(torrent.Href, "/") {
+		return k.BaseURL + strings.TrimPrefix(torrent.Href, "/")
+	}
+	return torrent.Href
+}
+
+// parseDetails fills torrent from its detail page
+func (k *KickassParser) parseDetails(doc *goquery.Document, torrent *TorrentFile) {
+	// getting magnetlink, the detail page wins over the listing one
+	if magnet := pickMagnet(doc.Find(`a[href^="magnet:"]`), *torrent); magnet != "" {
+		torrent.MagnetLink = magnet
+	}
+	torrent.TorrentURL = findTorrentURL(doc, `a[title="Download torrent file"], a[href$=".torrent"]`)
+
+	doc.Find("div.dataList li").Each(func(i int, s *goquery.Selection) {
+		header := strings.TrimSpace(s.Find("strong").First().Text())
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s.Text()), header))
+
+		switch strings.TrimSuffix(header, ":") {
+		case "Category":
+			if value != "" {
+				torrent.Category = strings.TrimSpace(strings.Split(value, ">")[0])
+			}
+
+		case "Language":
+			torrent.Language = value
+
+		case "Downloads":
+			if downloads, err := strconv.Atoi(strings.ReplaceAll(value, ",", "")); err == nil {
+				torrent.Downloads = downloads
+			}
+		}
+	})
+
+	if torrent.Uploader == "" {
+		torrent.Uploader = strings.TrimSpace(doc.Find(`a[href^="/user/"]`).First().Text())
+	}
+
+	torrent.MetaInfo = strings.TrimSpace(doc.Find("#desc").Text())
+
+	torrent.Files = parseFileList(doc.Find("table.torrentFileList tr"), func(row *goquery.Selection) (string, string) {
+		return row.Find("td.torFileName").Text(), row.Find("td.torFileSize").Text()
+	})
+
+	fillReleaseInfo(torrent, torrent.MetaInfo)
+
+	torrent.Trusted = isTrustedUploader(torrent.Uploader)
+	verifyMagnet(torrent)
+	torrent.Flags = SuspiciousFlags(*torrent)
+}
+
+func (k *KickassParser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	return enrichConcurrently(ctx, torrents, opts, k.FetchTorrentDetails)
+}
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestKickassParseTableRow(t *testing.T) {
+	k := NewKickassParser("https://kat.invalid/")
+	torrents := parseFixtureRows(t, "kickass_search.html", k.layout(), k.ParseTableRow)
+
+	want := []TorrentFile{
+		{
+			Name:       "The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.DTS-HD.MA.5.1-SWTYBLZ",
+			Href:       "/the-matrix-1999-2160p-uhd-bluray-x265-t7123401.html",
+			SizeRaw:    "18.2 GB",
+			Size:       18.2,
+			Seeders:    145,
+			Leechers:   22,
+			Uploader:   "SWTYBLZ",
+			Category:   "Movies",
+			UploadDate: "2 years",
+		},
+		{
+			Name:       "The Matrix (1999) [1080p] [BluRay] [YTS]",
+			Href:       "/the-matrix-1999-1080p-bluray-x264-yts-t7123402.html",
+			SizeRaw:    "2.1 GB",
+			Size:       2.1,
+			Seeders:    602,
+			Leechers:   58,
+			Uploader:   "YTS",
+			Category:   "Movies",
+			UploadDate: "3 years",
+		},
+		{
+			Name:       "The.Matrix.Resurrections.2021.720p.WEB-DL.DDP5.1.H.264-EVO",
+			Href:       "/the-matrix-resurrections-2021-720p-web-dl-t7123403.html",
+			SizeRaw:    "1.3 GB",
+			Size:       1.3,
+			Seeders:    64,
+			Leechers:   7,
+			Uploader:   "EVO",
+			Category:   "Movies",
+			UploadDate: "2 years",
+		},
+	}
+
+	if len(torrents) != len(want) {
+		t.Fatalf("parsed %d rows, want %d", len(torrents), len(want))
+	}
+	for i, w := range want {
+		got := torrents[i]
+		if got.Name != w.Name || got.Href != w.Href || got.SizeRaw != w.SizeRaw || got.Size != w.Size ||
+			got.Seeders != w.Seeders || got.Leechers != w.Leechers || got.Uploader != w.Uploader ||
+			got.Category != w.Category || got.UploadDate != w.UploadDate || got.MagnetLink != "" {
+			t.Errorf("row %d:\n got  %+v\n want %+v", i, got, w)
+		}
+	}
+}
+
+// parseKickassRow runs ParseTableRow on a single listing row
+func parseKickassRow(t *testing.T, row string) *TorrentFile {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<table class="data">` + row + `</table>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKickassParser("https://kat.invalid/").ParseTableRow(doc.Find(kickassLayout.Rows).First())
+}
+
+func TestKickassParseTableRowMagnet(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98&dn=The.Matrix.1999"
+	torrent := parseKickassRow(t, `<tr class="odd">
+		<td><div class="torrentname">
+			<a href="`+magnet+`" title="Torrent magnet link"><i class="ka ka-magnet"></i></a>
+			<a href="/the-matrix-t1.html" class="cellMainLink">The.Matrix.1999</a>
+		</div></td>
+		<td>1.4 GB</td><td>someone</td><td>1 year</td><td>12</td><td>3</td>
+	</tr>`)
+
+	if torrent == nil || torrent.MagnetLink != magnet {
+		t.Fatalf("torrent = %+v, want the listing magnet", torrent)
+	}
+	if torrent.Seeders != 12 || torrent.Leechers != 3 || torrent.Size != 1.4 {
+		t.Errorf("size/seeders/leechers = %v/%d/%d", torrent.Size, torrent.Seeders, torrent.Leechers)
+	}
+}
+
+func TestKickassParseTableRowMissingColumns(t *testing.T) {
+	torrent := parseKickassRow(t, `<tr class="even">
+		<td><div class="torrentname">
+			<a href="/the-matrix-t2.html" class="cellMainLink">The Matrix</a>
+			<span>Posted by <a href="/user/uploader/">uploader</a></span>
+		</div></td>
+	</tr>`)
+
+	if torrent == nil {
+		t.Fatal("a row with only the name cell was dropped")
+	}
+	if torrent.Name != "The Matrix" || torrent.Href != "/the-matrix-t2.html" {
+		t.Errorf("name %q href %q", torrent.Name, torrent.Href)
+	}
+	if torrent.Uploader != "uploader" {
+		t.Errorf("Uploader = %q, want it from the posted by link", torrent.Uploader)
+	}
+	if torrent.SizeRaw != "" || torrent.Size != 0 || torrent.Seeders != 0 || torrent.Leechers != 0 || torrent.Category != "" {
+		t.Errorf("missing columns filled in: %+v", torrent)
+	}
+
+	if torrent := parseKickassRow(t, `<tr class="odd"><td>no link</td><td>1 GB</td></tr>`); torrent != nil {
+		t.Errorf("a row without a link parsed into %+v", torrent)
+	}
+}
+
+func TestKickassParseDetails(t *testing.T) {
+	k := NewKickassParser("https://kat.invalid/")
+	torrents := parseFixtureRows(t, "kickass_search.html", k.layout(), k.ParseTableRow)
+	if len(torrents) == 0 {
+		t.Fatal("no rows in the search fixture")
+	}
+
+	doc, err := loadFixture("kickass_details.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := torrents[0]
+	k.parseDetails(doc, &torrent)
+
+	wantMagnet := "magnet:?xt=urn:btih:fedcba9876543210fedcba9876543210fedcba98&dn=The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.DTS-HD.MA.5.1-SWTYBLZ&tr=udp%3A%2F%2Ftracker.opentrackr.org%3A1337%2Fannounce"
+	if torrent.MagnetLink != wantMagnet {
+		t.Errorf("MagnetLink = %q, want %q", torrent.MagnetLink, wantMagnet)
+	}
+	if torrent.InfoHash != "fedcba9876543210fedcba9876543210fedcba98" {
+		t.Errorf("InfoHash = %q", torrent.InfoHash)
+	}
+	if torrent.Category != "Movies" || torrent.Language != "English" || torrent.Downloads != 2417 {
+		t.Errorf("category %q language %q downloads %d, want Movies English 2417", torrent.Category, torrent.Language, torrent.Downloads)
+	}
+	if torrent.Uploader != "SWTYBLZ" {
+		t.Errorf("Uploader = %q, want SWTYBLZ", torrent.Uploader)
+	}
+	if torrent.Name != "The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.DTS-HD.MA.5.1-SWTYBLZ" || torrent.Href != "/the-matrix-1999-2160p-uhd-bluray-x265-t7123401.html" {
+		t.Errorf("name %q href %q changed", torrent.Name, torrent.Href)
+	}
+	if torrent.Size != 18.2 || torrent.Seeders != 145 || torrent.Leechers != 22 {
+		t.Errorf("size/seeders/leechers = %v/%d/%d, want 18.2/145/22", torrent.Size, torrent.Seeders, torrent.Leechers)
+	}
+	if len(torrent.Files) != 1 || !strings.HasSuffix(torrent.Files[0].Path, "SWTYBLZ.mkv") {
+		t.Errorf("Files = %+v", torrent.Files)
+	}
+	if !strings.Contains(torrent.MetaInfo, "DTS-HD MA 5.1") {
+		t.Errorf("MetaInfo = %q", torrent.MetaInfo)
+	}
+	if slices.Contains(torrent.Flags, FlagMagnetMismatch) {
+		t.Errorf("Flags = %v, the detail magnet belongs to this torrent", torrent.Flags)
+	}
+}
+
+func TestKickassDetailURL(t *testing.T) {
+	k := NewKickassParser("https://kat.invalid/")
+	if got := k.detailURL(TorrentFile{Href: "/the-matrix-t1.html"}); got != "https://kat.invalid/the-matrix-t1.html" {
+		t.Errorf("detailURL = %q", got)
+	}
+	if got := k.detailURL(TorrentFile{Href: "https://other.invalid/t1.html"}); got != "https://other.invalid/t1.html" {
+		t.Errorf("detailURL of an absolute href = %q", got)
+	}
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"math"
+	"regexp"
+	"sanjaix21/krakeneye/internal/magnet"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var nameTokenPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+// pickMagnet returns the first link that parses and belongs to torrent, or the first one
+// that parses at all. Ads and half broken hrefs are skipped, "" when nothing is left.
+func pickMagnet(links *goquery.Selection, torrent TorrentFile) string {
+	var fallback string
+	var picked string
+
+	links.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		link, err := magnet.Parse(href)
+		if err != nil {
+			return true
+		}
+
+		if fallback == "" {
+			fallback = href
+		}
+		if magnetMatches(link, torrent) {
+			picked = href
+			return false
+		}
+		return true
+	})
+
+	if picked == "" {
+		return fallback
+	}
+	return picked
+}
+
+// findTorrentURL returns the first .torrent download link among links, resolved against
+// the page they are on
+func findTorrentURL(doc *goquery.Document, links string) string {
+	href, ok := doc.Find(links).First().Attr("href")
+	if !ok || strings.HasPrefix(href, "magnet:") {
+		return ""
+	}
+	return resolveURL(doc.Url, href)
+}
+
+// verifyMagnet drops a magnet link that doesn't parse and sets InfoHash from one that does
+func verifyMagnet(torrent *TorrentFile) {
+	if torrent.MagnetLink == "" {
+		return
+	}
+
+	link, err := magnet.Parse(torrent.MagnetLink)
+	if err != nil {
+		torrent.MagnetLink = ""
+		torrent.InfoHash = ""
+		return
+	}
+	torrent.InfoHash = link.Hash()
+}
+
+// listingInfoHash sets InfoHash from a magnet the search listing already carried, so
+// duplicates can be merged before any detail page is fetched. Unlike verifyMagnet it keeps
+// a magnet that doesn't parse, the detail page may still replace it.
+func listingInfoHash(torrent *TorrentFile) {
+	if torrent.InfoHash != "" || torrent.MagnetLink == "" {
+		return
+	}
+	if link, err := magnet.Parse(torrent.MagnetLink); err == nil {
+		torrent.InfoHash = link.Hash()
+	}
+}
+
+// magnetMatches checks that dn and xl roughly agree with the listing. A missing dn or xl
+// proves nothing either way.
+func magnetMatches(link magnet.Link, torrent TorrentFile) bool {
+	if link.Name != "" && torrent.Name != "" && !similarNames(link.Name, torrent.Name) {
+		return false
+	}
+
+	if link.Length > 0 && torrent.Size > 0 {
+		size := float64(link.Length) / (1024 * 1024 * 1024)
+		// listings round to one decimal, tiny torrents need some slack on top
+		if math.Abs(size-torrent.Size) > math.Max(torrent.Size*0.1, 0.05) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// similarNames compares the words of two names, listings cut long names short so at least
+// half the words of the shorter one have to show up in the other
+func similarNames(a string, b string) bool {
+	wordsA := nameTokenPattern.FindAllString(strings.ToLower(a), -1)
+	wordsB := nameTokenPattern.FindAllString(strings.ToLower(b), -1)
+	if len(wordsA) > len(wordsB) {
+		wordsA, wordsB = wordsB, wordsA
+	}
+	if len(wordsA) == 0 {
+		return true
+	}
+
+	inB := make(map[string]bool)
+	for _, word := range wordsB {
+		inB[word] = true
+	}
+
+	shared := 0
+	for _, word := range wordsA {
+		if inB[word] {
+			shared++
+		}
+	}
+	return shared*2 >= len(wordsA)
+}
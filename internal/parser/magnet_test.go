@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"sanjaix21/krakeneye/internal/magnet"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMagnetMatches(t *testing.T) {
+	torrent := TorrentFile{Name: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE", Size: 2.0}
+
+	tests := []struct {
+		name string
+		link magnet.Link
+		want bool
+	}{
+		{"no dn or xl", magnet.Link{}, true},
+		{"same name", magnet.Link{Name: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE"}, true},
+		{"name cut short by the listing", magnet.Link{Name: "The Matrix 1999 1080p"}, true},
+		{"another torrent", magnet.Link{Name: "Free.Casino.Bonus.Download.Now"}, false},
+		{"size within rounding", magnet.Link{Length: 2_100_000_000}, true},
+		{"size of another torrent", magnet.Link{Length: 700_000_000}, false},
+		{"right name, wrong size", magnet.Link{Name: torrent.Name, Length: 8 << 30}, false},
+	}
+
+	for _, tt := range tests {
+		if got := magnetMatches(tt.link, torrent); got != tt.want {
+			t.Errorf("%s: magnetMatches = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPickMagnet(t *testing.T) {
+	page := `<a href="magnet:?xt=urn:btih:nothex">broken</a>
+<a href="magnet:?xt=urn:btih:1111111111111111111111111111111111111111&dn=Casino.Bonus">ad</a>
+<a href="magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=The.Matrix.1999.1080p">real</a>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := doc.Find(`a[href^="magnet:"]`)
+
+	picked := pickMagnet(links, TorrentFile{Name: "The.Matrix.1999.1080p.BluRay.x264-AMIABLE"})
+	if !strings.Contains(picked, "0123456789abcdef") {
+		t.Errorf("picked %q, want the magnet whose dn matches", picked)
+	}
+
+	// nothing matches, the first one that parses is better than none
+	fallback := pickMagnet(links, TorrentFile{Name: "Completely.Different.Movie"})
+	if !strings.Contains(fallback, "1111111111") {
+		t.Errorf("fallback %q, want the first valid magnet", fallback)
+	}
+
+	torrent := TorrentFile{MagnetLink: "magnet:?xt=urn:btih:nothex"}
+	verifyMagnet(&torrent)
+	if torrent.MagnetLink != "" || torrent.InfoHash != "" {
+		t.Errorf("a broken magnet survived verifyMagnet: %+v", torrent)
+	}
+}
+
+func TestSuspiciousFlagsMagnetMismatch(t *testing.T) {
+	torrent := TorrentFile{
+		Name:       "The.Matrix.1999.1080p.BluRay.x264-AMIABLE",
+		Size:       2.0,
+		MagnetLink: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=Casino.Bonus.Setup&xl=1000",
+	}
+
+	flags := SuspiciousFlags(torrent)
+	if len(flags) != 1 || flags[0] != FlagMagnetMismatch {
+		t.Errorf("flags = %v, want only %s", flags, FlagMagnetMismatch)
+	}
+}
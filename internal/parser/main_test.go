@@ -0,0 +1,17 @@
+package parser
+
+import (
+	"os"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"testing"
+	"time"
+)
+
+// the tests talk to local httptest servers, retries and rate limits only slow them down
+func TestMain(m *testing.M) {
+	httpclient.Configure(httpclient.Options{
+		Timeout:     5 * time.Second,
+		DialTimeout: time.Second,
+	})
+	os.Exit(m.Run())
+}
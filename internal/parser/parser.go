@@ -1,39 +1,132 @@
 package parser
 
 import (
+	"context"
+	"fmt"
 	"regexp"
+	"sanjaix21/krakeneye/internal/mediainfo"
+	"sanjaix21/krakeneye/internal/release"
 	"strconv"
 	"strings"
 )
 
 type TorrentFile struct {
-	Name       string
-	Href       string
-	Size       float64
-	SizeRaw    string
-	SiteName   string
-	Seeders    int
-	Leechers   int
-	Uploader   string
-	MagnetLink string
-	Language   string
-	Downloads  int
-	MetaInfo   string
-	Source     string
-	UploadDate string
-	Category   string
-	Resolution string
-	Trusted    bool
-	VideoCodec string
-	AudioCodec string
-	Container  string
-	BitDepth   string
-	Score      float64
+	Name           string
+	Href           string
+	Size           float64
+	SizeRaw        string
+	SiteName       string
+	Sources        []string // every site the torrent was found on, SiteName first
+	Mirror         string   // mirror of SiteName that served the result
+	Seeders        int
+	Leechers       int
+	Uploader       string
+	MagnetLink     string
+	InfoHash       string // v1 info-hash in lowercase hex (v2 for v2 only torrents), from MagnetLink
+	TorrentURL     string // .torrent download the detail page offers, empty when there is none
+	Language       string
+	Downloads      int
+	MetaInfo       string
+	Source         string
+	UploadDate     string
+	Category       string
+	Resolution     string
+	Trusted        bool
+	VideoCodec     string
+	AudioCodec     string
+	AudioChannels  string   // widest layout of any track: 2.0, 5.1, 7.1
+	AudioTracks    int      // at least this many audio tracks, 0 when unknown
+	AudioLanguages []string // every audio language named, the site's own Language first
+	Container      string
+	BitDepth       string
+	HDRFormat      string            // HDR10, HDR10+, HLG, DV P8.1+HDR10 ... empty for SDR
+	Release        release.Info      // everything the name says, the fields above also use the description
+	MediaInfo      *mediainfo.Report // the MediaInfo dump in the description, nil when there is none
+	Files          []FileEntry       // as the detail page lists them, empty when it doesn't
+	Flags          []string          // suspicious content, see the Flag* constants
+	Score          float64
 }
 
+// TorrentParser is implemented by every site. Both calls stop fetching as soon as ctx is
+// cancelled or its deadline passes.
 type TorrentParser interface {
-	Search(query string) ([]TorrentFile, error)
-	EnrichTorrents(torrents []TorrentFile) []TorrentFile
+	Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error)
+	EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile
+}
+
+// SearchOptions limits how much of a site a single search scrapes
+type SearchOptions struct {
+	MaxPages   int    // result pages to follow, anything below 1 means just the first page
+	MaxResults int    // stop once this many results are collected, 0 means no limit
+	Category   string // one of the Category* constants, each parser maps it to its site's filter
+}
+
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		MaxPages: 1,
+	}
+}
+
+func (o SearchOptions) pages() int {
+	if o.MaxPages < 1 {
+		return 1
+	}
+	return o.MaxPages
+}
+
+// full reports whether collected results already reach MaxResults
+func (o SearchOptions) full(collected int) bool {
+	return o.MaxResults > 0 && collected >= o.MaxResults
+}
+
+// search categories, CategoryAll leaves the search unfiltered
+const (
+	CategoryAll    = ""
+	CategoryMovies = "movies"
+	CategoryTV     = "tv"
+	CategoryGames  = "games"
+	CategoryMusic  = "music"
+)
+
+// ParseCategory validates a user supplied category name
+func ParseCategory(category string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(category)); normalized {
+	case "", "all":
+		return CategoryAll, nil
+	case CategoryMovies, CategoryTV, CategoryGames, CategoryMusic:
+		return normalized, nil
+	default:
+		return "", fmt.Errorf("unknown category %q (use movies, tv, games or music)", category)
+	}
+}
+
+// NormalizeCategory maps a site's own category label onto a Category* constant,
+// labels it can't place come back as CategoryAll
+func NormalizeCategory(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+
+	switch {
+	case strings.HasPrefix(label, "movie"), strings.HasPrefix(label, "film"):
+		return CategoryMovies
+	case label == "tv", strings.HasPrefix(label, "tv "), strings.HasPrefix(label, "tv/"),
+		strings.HasPrefix(label, "television"):
+		return CategoryTV
+	case strings.HasPrefix(label, "game"):
+		return CategoryGames
+	case strings.HasPrefix(label, "music"), strings.HasPrefix(label, "audio"):
+		return CategoryMusic
+	default:
+		return CategoryAll
+	}
+}
+
+// matchesCategory keeps unlabelled results, sites don't always label rows
+func (o SearchOptions) matchesCategory(label string) bool {
+	if o.Category == CategoryAll || strings.TrimSpace(label) == "" {
+		return true
+	}
+
+	return NormalizeCategory(label) == o.Category
 }
 
 func ParseSizeToGB(sizeStr string) float64 {
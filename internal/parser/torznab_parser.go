@@ -0,0 +1,368 @@
+package parser
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"sanjaix21/krakeneye/internal/magnet"
+	"strconv"
+	"strings"
+)
+
+// TorznabParser queries a Torznab endpoint (Jackett, Prowlarr, ...) instead of scraping HTML
+type TorznabParser struct {
+	Endpoint string
+	APIKey   string
+}
+
+// NewTorznabParser takes the endpoint URL, an apikey query parameter on it wins over apiKey
+func NewTorznabParser(endpoint string, apiKey string) *TorznabParser {
+	if parsed, err := url.Parse(endpoint); err == nil {
+		query := parsed.Query()
+		if key := query.Get("apikey"); key != "" {
+			apiKey = key
+			query.Del("apikey")
+			parsed.RawQuery = query.Encode()
+			endpoint = parsed.String()
+		}
+	}
+
+	return &TorznabParser{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+	}
+}
+
+func newTorznabParserFromEnv(endpoint string) *TorznabParser {
+	return NewTorznabParser(endpoint, os.Getenv("KRAKENEYE_TORZNAB_APIKEY"))
+}
+
+type torznabFeed struct {
+	Channel struct {
+		Items []torznabItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabItem struct {
+	Title      string   `xml:"title"`
+	GUID       string   `xml:"guid"`
+	Link       string   `xml:"link"`
+	Comments   string   `xml:"comments"`
+	PubDate    string   `xml:"pubDate"`
+	Size       int64    `xml:"size"`
+	Categories []string `xml:"category"`
+	Enclosure  struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Attrs []torznabAttr `xml:"http://torznab.com/schemas/2015/feed attr"`
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabError struct {
+	Code        int    `xml:"code,attr"`
+	Description string `xml:"description,attr"`
+}
+
+// newznab top level category ids
+var torznabCategories = map[string]string{
+	"1": "Games",
+	"2": "Movies",
+	"3": "Music",
+	"4": "Apps",
+	"5": "TV",
+	"6": "XXX",
+	"7": "Books",
+	"8": "Other",
+}
+
+// search categories to newznab category ids
+var torznabCategoryFilters = map[string]string{
+	CategoryMovies: "2000",
+	CategoryTV:     "5000",
+	CategoryGames:  "1000",
+	CategoryMusic:  "3000",
+}
+
+// torznab pages are offset based, this many items per request
+const torznabPageSize = 100
+
+func (t *TorznabParser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	pageSize := torznabPageSize
+	if opts.MaxResults > 0 && opts.MaxResults < pageSize {
+		pageSize = opts.MaxResults
+	}
+
+	var torrents []TorrentFile
+	seen := make(map[string]bool)
+
+	for page := 0; page < opts.pages(); page++ {
+		params := url.Values{}
+		params.Set("t", "search")
+		params.Set("q", query)
+		params.Set("limit", strconv.Itoa(pageSize))
+		params.Set("offset", strconv.Itoa(page*pageSize))
+		if cat, ok := torznabCategoryFilters[opts.Category]; ok {
+			params.Set("cat", cat)
+		}
+
+		items, err := t.fetchItems(ctx, params)
+		if err != nil {
+			if page == 0 || ctx.Err() != nil {
+				return nil, fmt.Errorf("torznab search failed: %w", err)
+			}
+			log.Printf("⚠️ Warning: stopping at page %d: %v", page+1, err)
+			break
+		}
+
+		fresh := 0
+		for _, item := range items {
+			torrent := t.parseItem(item)
+			if torrent == nil {
+				continue
+			}
+			// an item without any key can't be told apart, it is kept
+			if key := torznabItemKey(*torrent); key != "" {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			fresh++
+
+			if opts.matchesCategory(torrent.Category) && !opts.full(len(torrents)) {
+				torrents = append(torrents, *torrent)
+			}
+		}
+
+		// a short page is the last one
+		if fresh == 0 || len(items) < pageSize || opts.full(len(torrents)) {
+			break
+		}
+	}
+
+	if len(torrents) <= 0 {
+		return nil, fmt.Errorf("torznab search failed: %w", ErrNoResults)
+	}
+	fmt.Printf("📦 Found %d torrents\n", len(torrents))
+	return torrents, nil
+}
+
+// torznabItemKey is what identifies an item across pages: its info-hash, else its magnet,
+// .torrent link or details page. Many feeds leave comments and guid out.
+func torznabItemKey(torrent TorrentFile) string {
+	if link, err := magnet.Parse(torrent.MagnetLink); err == nil {
+		return link.Hash()
+	}
+	for _, key := range []string{torrent.MagnetLink, torrent.TorrentURL, torrent.Href} {
+		if key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+func (t *TorznabParser) fetchItems(ctx context.Context, params url.Values) ([]torznabItem, error) {
+	resp, err := httpclient.Default().Get(ctx, t.requestURL(params))
+	if err != nil {
+		return nil, unreachableError(ctx, t.Endpoint, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("⚠️ Warning: failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: t.Endpoint, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torznab response: %w", err)
+	}
+
+	return decodeTorznabFeed(body)
+}
+
+// verify asks the endpoint for its capabilities, a torznab server answers with <caps>
+func (t *TorznabParser) verify(ctx context.Context, client *httpclient.Client) error {
+	resp, err := client.Get(ctx, t.requestURL(url.Values{"t": {"caps"}}))
+	if err != nil {
+		return unreachableError(ctx, t.Endpoint, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("⚠️ Warning: failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: t.Endpoint, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return unreachableError(ctx, t.Endpoint, err)
+	}
+
+	var root struct {
+		XMLName xml.Name
+		torznabError
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return fmt.Errorf("%w: %s answered with no XML", ErrNotSite, t.Endpoint)
+	}
+
+	switch root.XMLName.Local {
+	case "caps":
+		return nil
+	case "error":
+		return fmt.Errorf("torznab error %d: %s", root.Code, root.Description)
+	default:
+		return fmt.Errorf("%w: %s answered with <%s> instead of <caps>", ErrNotSite, t.Endpoint, root.XMLName.Local)
+	}
+}
+
+// requestURL is the endpoint with params and the apikey added
+func (t *TorznabParser) requestURL(params url.Values) string {
+	if t.APIKey != "" {
+		params.Set("apikey", t.APIKey)
+	}
+
+	if strings.Contains(t.Endpoint, "?") {
+		return t.Endpoint + "&" + params.Encode()
+	}
+	return t.Endpoint + "?" + params.Encode()
+}
+
+// decodeTorznabFeed handles both the rss feed and the <error code=".." /> reply
+func decodeTorznabFeed(body []byte) ([]torznabItem, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse torznab XML: %w", err)
+	}
+
+	if root.XMLName.Local == "error" {
+		var apiError torznabError
+		if err := xml.Unmarshal(body, &apiError); err != nil {
+			return nil, fmt.Errorf("failed to parse torznab error: %w", err)
+		}
+		return nil, fmt.Errorf("torznab error %d: %s", apiError.Code, apiError.Description)
+	}
+
+	var feed torznabFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse torznab feed: %w", err)
+	}
+
+	return feed.Channel.Items, nil
+}
+
+func (t *TorznabParser) parseItem(item torznabItem) *TorrentFile {
+	if item.Title == "" {
+		return nil
+	}
+
+	torrent := &TorrentFile{
+		Name:       strings.TrimSpace(item.Title),
+		Href:       item.Comments,
+		UploadDate: item.PubDate,
+	}
+	if torrent.Href == "" {
+		torrent.Href = item.GUID
+	}
+
+	attrs := make(map[string]string)
+	for _, attr := range item.Attrs {
+		// attributes like category can repeat, keep the first
+		if _, exists := attrs[attr.Name]; !exists {
+			attrs[attr.Name] = attr.Value
+		}
+	}
+
+	// Size
+	size := item.Size
+	if size == 0 {
+		size = item.Enclosure.Length
+	}
+	if attrSize, err := strconv.ParseInt(attrs["size"], 10, 64); err == nil && size == 0 {
+		size = attrSize
+	}
+	if size > 0 {
+		torrent.Size = float64(size) / (1024 * 1024 * 1024)
+		torrent.SizeRaw = fmt.Sprintf("%.2f GB", torrent.Size)
+	}
+
+	// Category, ids above 9999 are indexer specific and tell us nothing
+	for _, category := range append(item.Categories, attrs["category"]) {
+		if len(category) == 4 {
+			torrent.Category = torznabCategories[category[:1]]
+			break
+		}
+	}
+
+	// Seeders and Leechers, peers counts seeders too
+	if seeders, err := strconv.Atoi(attrs["seeders"]); err == nil {
+		torrent.Seeders = seeders
+	}
+	if leechers, err := strconv.Atoi(attrs["leechers"]); err == nil {
+		torrent.Leechers = leechers
+	} else if peers, err := strconv.Atoi(attrs["peers"]); err == nil && peers >= torrent.Seeders {
+		torrent.Leechers = peers - torrent.Seeders
+	}
+
+	// Downloads
+	if grabs, err := strconv.Atoi(attrs["grabs"]); err == nil {
+		torrent.Downloads = grabs
+	}
+
+	// Magnet, some indexers only put it in the link
+	torrent.MagnetLink = attrs["magneturl"]
+	if torrent.MagnetLink == "" && strings.HasPrefix(item.Link, "magnet:") {
+		torrent.MagnetLink = item.Link
+	}
+	if strings.HasPrefix(item.Enclosure.URL, "http") {
+		torrent.TorrentURL = item.Enclosure.URL
+	}
+	// or just the hash, which is enough to build one
+	if hash, err := magnet.NormalizeInfoHash(attrs["infohash"]); err == nil && torrent.MagnetLink == "" {
+		torrent.MagnetLink = magnet.Link{InfoHash: hash, Name: torrent.Name}.String()
+	}
+
+	torrent.Uploader = attrs["poster"]
+	torrent.Language = attrs["language"]
+	torrent.MetaInfo = attrs["description"]
+
+	return torrent
+}
+
+// FetchTorrentDetails has nothing to fetch, the feed already carries everything,
+// so it only derives the release metadata from the name
+func (t *TorznabParser) FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error {
+	fillReleaseInfo(torrent, torrent.MetaInfo)
+
+	torrent.Trusted = isTrustedUploader(torrent.Uploader)
+	verifyMagnet(torrent)
+	torrent.Flags = SuspiciousFlags(*torrent)
+
+	return nil
+}
+
+func (t *TorznabParser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	return enrichConcurrently(ctx, torrents, opts, t.FetchTorrentDetails)
+}
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const torznabTestFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+  <title>Jackett</title>
+  <item>
+    <title>The.Matrix.1999.1080p.BluRay.x264-AMIABLE</title>
+    <guid>https://indexer.invalid/details/1</guid>
+    <comments>https://indexer.invalid/details/1</comments>
+    <pubDate>Sat, 04 Mar 2023 10:00:00 +0000</pubDate>
+    <size>2147483648</size>
+    <category>2040</category>
+    <enclosure url="https://indexer.invalid/download/1.torrent" length="2147483648" type="application/x-bittorrent" />
+    <torznab:attr name="category" value="2040" />
+    <torznab:attr name="category" value="100001" />
+    <torznab:attr name="seeders" value="120" />
+    <torznab:attr name="peers" value="150" />
+    <torznab:attr name="grabs" value="3000" />
+    <torznab:attr name="magneturl" value="magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&amp;dn=The.Matrix.1999.1080p.BluRay.x264-AMIABLE" />
+  </item>
+  <item>
+    <title>The.Matrix.Resurrections.2021.2160p.WEB-DL.DDP5.1.HEVC-EVO</title>
+    <guid>https://indexer.invalid/details/2</guid>
+    <size>314572800</size>
+    <torznab:attr name="category" value="5040" />
+    <torznab:attr name="seeders" value="8" />
+    <torznab:attr name="leechers" value="2" />
+    <torznab:attr name="infohash" value="89ABCDEF0123456789ABCDEF0123456789ABCDEF" />
+  </item>
+</channel>
+</rss>`
+
+// torznabServer answers every search with torznabTestFeed, or with the error reply of an
+// indexer when the apikey isn't key
+func torznabServer(t *testing.T, key string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		if r.URL.Query().Get("apikey") != key {
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><error code="100" description="Invalid API Key" />`))
+			return
+		}
+		_, _ = w.Write([]byte(torznabTestFeed))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTorznabSearch(t *testing.T) {
+	server := torznabServer(t, "secret")
+	torznab := NewTorznabParser(server.URL+"/api", "secret")
+
+	torrents, err := torznab.Search(context.Background(), "the matrix", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 2 {
+		t.Fatalf("got %d torrents, want 2", len(torrents))
+	}
+
+	movie := torrents[0]
+	if movie.Name != "The.Matrix.1999.1080p.BluRay.x264-AMIABLE" || movie.Href != "https://indexer.invalid/details/1" {
+		t.Errorf("name/href = %q %q", movie.Name, movie.Href)
+	}
+	if movie.Size != 2 || movie.SizeRaw != "2.00 GB" {
+		t.Errorf("size = %v %q, want 2 GB", movie.Size, movie.SizeRaw)
+	}
+	if movie.Seeders != 120 || movie.Leechers != 30 {
+		t.Errorf("seeders/leechers = %d/%d, want 120/30 from peers", movie.Seeders, movie.Leechers)
+	}
+	if movie.Downloads != 3000 || movie.Category != "Movies" {
+		t.Errorf("downloads/category = %d %q", movie.Downloads, movie.Category)
+	}
+	if !strings.HasPrefix(movie.MagnetLink, "magnet:?xt=urn:btih:0123456789abcdef") || movie.TorrentURL != "https://indexer.invalid/download/1.torrent" {
+		t.Errorf("magnet/torrent = %q %q", movie.MagnetLink, movie.TorrentURL)
+	}
+
+	show := torrents[1]
+	if show.Category != "TV" || show.Seeders != 8 || show.Leechers != 2 {
+		t.Errorf("category/seeders/leechers = %q %d/%d, want TV 8/2", show.Category, show.Seeders, show.Leechers)
+	}
+	if !strings.Contains(show.MagnetLink, "urn:btih:89abcdef0123456789abcdef0123456789abcdef") {
+		t.Errorf("magnet built from infohash = %q", show.MagnetLink)
+	}
+}
+
+func TestTorznabSearchCategory(t *testing.T) {
+	server := torznabServer(t, "")
+	torznab := NewTorznabParser(server.URL+"/api", "")
+
+	torrents, err := torznab.Search(context.Background(), "the matrix", SearchOptions{Category: CategoryTV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrents) != 1 || torrents[0].Category != "TV" {
+		t.Errorf("got %+v, want only the TV item", torrents)
+	}
+}
+
+func TestTorznabErrorReply(t *testing.T) {
+	server := torznabServer(t, "secret")
+	torznab := NewTorznabParser(server.URL+"/api", "wrong")
+
+	_, err := torznab.Search(context.Background(), "the matrix", SearchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "torznab error 100: Invalid API Key") {
+		t.Fatalf("err = %v, want the indexer's error reply", err)
+	}
+	if errors.Is(err, ErrNoResults) {
+		t.Error("an error reply must not look like an empty search")
+	}
+}
+
+func TestTorznabAPIKeyFromEndpoint(t *testing.T) {
+	server := torznabServer(t, "secret")
+	torznab := NewTorznabParser(server.URL+"/api?apikey=secret", "ignored")
+
+	if torznab.APIKey != "secret" || strings.Contains(torznab.Endpoint, "apikey") {
+		t.Fatalf("endpoint %q key %q, want the key moved off the endpoint", torznab.Endpoint, torznab.APIKey)
+	}
+	if _, err := torznab.Search(context.Background(), "the matrix", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTorznabFetchTorrentDetails(t *testing.T) {
+	server := torznabServer(t, "")
+	torznab := NewTorznabParser(server.URL+"/api", "")
+
+	torrents, err := torznab.Search(context.Background(), "the matrix", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrents = torznab.EnrichTorrents(context.Background(), torrents, EnrichOptions{})
+
+	movie := torrents[0]
+	if movie.InfoHash != "0123456789abcdef0123456789abcdef01234567" || movie.Resolution != "1080P" {
+		t.Errorf("info-hash/resolution = %q %q", movie.InfoHash, movie.Resolution)
+	}
+	if len(movie.Flags) != 0 {
+		t.Errorf("flags = %v, want none", movie.Flags)
+	}
+
+	// 0.29 GB is too small for a 2160p episode
+	show := torrents[1]
+	if !slices.Contains(show.Flags, FlagSizeMismatch) {
+		t.Errorf("flags = %v, want %s", show.Flags, FlagSizeMismatch)
+	}
+}
+
+// Jackett and Prowlarr often leave out comments and guid
+const torznabFeedWithoutLinks = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+  <item>
+    <title>The.Matrix.1999.1080p.BluRay.x264-AMIABLE</title>
+    <torznab:attr name="magneturl" value="magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&amp;dn=The.Matrix.1999" />
+  </item>
+  <item>
+    <title>The.Matrix.1999.720p.BluRay.x264-SiNNERS</title>
+    <enclosure url="https://indexer.invalid/download/2.torrent" length="1073741824" type="application/x-bittorrent" />
+  </item>
+  <item>
+    <title>The Matrix 1999 1080p BluRay x264 AMIABLE</title>
+    <torznab:attr name="infohash" value="0123456789ABCDEF0123456789ABCDEF01234567" />
+  </item>
+  <item>
+    <title>The.Matrix.1999.DVDRip.XviD</title>
+  </item>
+  <item>
+    <title>The.Matrix.1999.2160p.UHD.BluRay.x265-TERMiNAL</title>
+    <torznab:attr name="infohash" value="89abcdef0123456789abcdef0123456789abcdef" />
+  </item>
+</channel>
+</rss>`
+
+func TestTorznabSearchWithoutLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(torznabFeedWithoutLinks))
+	}))
+	t.Cleanup(server.Close)
+	torznab := NewTorznabParser(server.URL+"/api", "")
+
+	torrents, err := torznab.Search(context.Background(), "the matrix", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, torrent := range torrents {
+		names = append(names, torrent.Name)
+	}
+	want := []string{
+		"The.Matrix.1999.1080p.BluRay.x264-AMIABLE",
+		"The.Matrix.1999.720p.BluRay.x264-SiNNERS",
+		"The.Matrix.1999.DVDRip.XviD",
+		"The.Matrix.1999.2160p.UHD.BluRay.x265-TERMiNAL",
+	}
+	// the third item repeats the first one's info-hash
+	if !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// VerifyMirror checks that mirrorURL serves siteName in a shape its parser can read. HTML
+// sites get one results page searched for DefaultHealthQuery, which has to match the
+// parser's own selectors, torznab endpoints have to answer t=caps. A challenge page fails
+// with ErrBlocked, a parked domain or captive portal with ErrNotSite.
+func VerifyMirror(ctx context.Context, client *httpclient.Client, siteName string, mirrorURL string) error {
+	torrentParser, err := NewParser(siteName, mirrorURL)
+	if err != nil {
+		return err
+	}
+
+	switch p := torrentParser.(type) {
+	case *TorznabParser:
+		return p.verify(ctx, client)
+	case scraper:
+		pageURL := p.searchURL(DefaultHealthQuery, DefaultSearchOptions())
+		doc, err := fetchDocumentWith(ctx, client, pageURL)
+		if err != nil {
+			return err
+		}
+		if !looksLikeListing(doc, p.layout()) {
+			return fmt.Errorf("%w: %s matched none of the %s selectors", ErrNotSite, pageURL, siteName)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// looksLikeListing reports whether doc is a results page of the layout, empty or not
+func looksLikeListing(doc *goquery.Document, layout listingLayout) bool {
+	if doc.Find(layout.Rows).Length() > 0 {
+		return true
+	}
+	if layout.Results != "" && doc.Find(layout.Results).Length() > 0 {
+		return true
+	}
+	return layout.NoResults != "" && strings.Contains(doc.Text(), layout.NoResults)
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type X1337Parser struct {
+	BaseURL string
+}
+
+func NewX1337Parser(mirrorURL string) *X1337Parser {
+	return &X1337Parser{
+		BaseURL: mirrorURL,
+	}
+}
+
+// search categories to 1337x's category-search path segment
+var x1337Categories = map[string]string{
+	CategoryMovies: "Movies",
+	CategoryTV:     "TV",
+	CategoryGames:  "Games",
+	CategoryMusic:  "Music",
+}
+
+// 1337x sub category ids (from the icon link /sub/<id>/0/) to their top category
+var x1337SubCategories = map[string]string{
+	"1": "Movies", "2": "Movies", "3": "Movies", "4": "Movies", "42": "Movies",
+	"54": "Movies", "66": "Movies", "70": "Movies", "73": "Movies", "76": "Movies",
+	"5": "TV", "6": "TV", "7": "TV", "9": "TV", "41": "TV", "71": "TV", "74": "TV", "75": "TV",
+	"10": "Games", "11": "Games", "12": "Games", "13": "Games", "14": "Games", "15": "Games",
+	"16": "Games", "17": "Games", "43": "Games", "44": "Games", "45": "Games", "46": "Games",
+	"22": "Music", "23": "Music", "24": "Music", "25": "Music", "26": "Music", "27": "Music", "53": "Music",
+	"18": "Apps", "19": "Apps", "20": "Apps", "21": "Apps", "56": "Apps", "57": "Apps", "58": "Apps",
+	"28": "Anime", "78": "Anime", "79": "Anime", "80": "Anime",
+	"33": "Other", "34": "Other", "35": "Other", "36": "Other", "37": "Other", "38": "Other", "39": "Other", "40": "Other",
+}
+
+// x1337Layout is what X1337Parser expects of a search results page
+var x1337Layout = listingLayout{
+	Results:   "table.table-list",
+	Rows:      "table.table-list tbody tr",
+	Pager:     "div.pagination li a",
+	NoResults: "No results were returned",
+}
+
+func (x *X1337Parser) Search(ctx context.Context, query string, opts SearchOptions) ([]TorrentFile, error) {
+	searchURL := x.searchURL(query, opts)
+	fmt.Println("🌍 Search URL:", searchURL)
+
+	torrents, err := paginate(ctx, searchURL, opts, x.layout(), x.ParseTableRow)
+	if err != nil {
+		return nil, fmt.Errorf("1337x search failed: %w", err)
+	}
+
+	fmt.Printf("📦 Found %d torrents\n", len(torrents))
+	return torrents, nil
+}
+
+// searchURL is the first results page for query
+func (x *X1337Parser) searchURL(query string, opts SearchOptions) string {
+	escapedQuery := url.PathEscape(strings.ReplaceAll(query, " ", "+"))
+	searchURL := fmt.Sprintf("%ssearch/%s/1/", x.BaseURL, escapedQuery)
+	if category, ok := x1337Categories[opts.Category]; ok {
+		searchURL = fmt.Sprintf("%scategory-search/%s/%s/1/", x.BaseURL, escapedQuery, category)
+	}
+	return searchURL
+}
+
+func (x *X1337Parser) layout() listingLayout {
+	return x1337Layout
+}
+
+func (x *X1337Parser) ParseTableRow(s *goquery.Selection) *TorrentFile {
+	nameCell := s.Find("td.coll-1")
+	linkElement := nameCell.Find(`a[href^="/torrent/"]`).First()
+
+	href, exists := linkElement.Attr("href")
+	if !exists {
+		return nil // skip invalid rows
+	}
+
+	torrent := &TorrentFile{
+		Name: strings.TrimSpace(linkElement.Text()),
+		Href: href,
+	}
+
+	// Category
+	if iconHref, ok := nameCell.Find(`a[href^="/sub/"]`).Attr("href"); ok {
+		parts := strings.Split(strings.Trim(iconHref, "/"), "/")
+		if len(parts) >= 2 {
+			torrent.Category = x1337SubCategories[parts[1]]
+		}
+	}
+
+	// UploadDate
+	torrent.UploadDate = strings.TrimSpace(s.Find("td.coll-date").Text())
+
+	// Size, the cell also carries a hidden seeders span for mobile layouts
+	sizeCell := s.Find("td.coll-4").Clone()
+	sizeCell.Find("span").Remove()
+	if sizeText := strings.TrimSpace(sizeCell.Text()); sizeText != "" {
+		torrent.SizeRaw = sizeText
+		torrent.Size = ParseSizeToGB(sizeText)
+	}
+
+	// Seeders
+	if seeders, err := strconv.Atoi(strings.TrimSpace(s.Find("td.coll-2").Text())); err == nil {
+		torrent.Seeders = seeders
+	}
+
+	// Leechers
+	if leechers, err := strconv.Atoi(strings.TrimSpace(s.Find("td.coll-3").Text())); err == nil {
+		torrent.Leechers = leechers
+	}
+
+	// Uploader
+	torrent.Uploader = strings.TrimSpace(s.Find("td.coll-5").Text())
+
+	return torrent
+}
+
+func (x *X1337Parser) FetchTorrentDetails(ctx context.Context, torrent *TorrentFile) error {
+	doc, err := fetchDocument(ctx, x.detailURL(*torrent))
+	if err != nil {
+		return fmt.Errorf("failed to fetch torrent details: %w", err)
+	}
+
+	x.parseDetails(doc, torrent)
+	return nil
+}
+
+func (x *X1337Parser) detailURL(torrent TorrentFile) string {
+	if strings.HasPrefix(torrent.Href, "/") {
+		return x.BaseURL + strings.TrimPrefix(torrent.Href, "/")
+	}
+	return torrent.Href
+}
+
+// parseDetails fills torrent from its detail page
+func (x *X1337Parser) parseDetails(doc *goquery.Document, torrent *TorrentFile) {
+	// getting magnetlink
+	if magnet := pickMagnet(doc.Find(`a[href^="magnet:"]`), *torrent); magnet != "" {
+		torrent.MagnetLink = magnet
+	}
+	// the torrent download buttons point at cache sites like itorrents
+	torrent.TorrentURL = findTorrentURL(doc, `ul.dropdown-menu a[href$=".torrent"], a[href*="/torrent/"][href$=".torrent"]`)
+
+	var releaseType string
+	doc.Find("ul.list li").Each(func(i int, s *goquery.Selection) {
+		header := strings.TrimSpace(s.Find("strong").Text())
+		value := strings.TrimSpace(s.Find("span").First().Text())
+
+		switch header {
+		case "Category":
+			torrent.Category = value
+
+		case "Type":
+			releaseType = value
+
+		case "Language":
+			torrent.Language = value
+
+		case "Downloads":
+			if downloads, err := strconv.Atoi(value); err == nil {
+				torrent.Downloads = downloads
+			}
+
+		case "Uploaded By":
+			if torrent.Uploader == "" {
+				torrent.Uploader = value
+			}
+		}
+	})
+
+	torrent.MetaInfo = strings.TrimSpace(doc.Find("#description").Text())
+
+	// file tab items read "The.Matrix.mkv (19.8 GB)", folders are li.head
+	torrent.Files = parseFileList(doc.Find(".file-content li:not(.head)"), func(item *goquery.Selection) (string, string) {
+		return splitSizeSuffix(item.Text())
+	})
+
+	fillReleaseInfo(torrent, releaseType+" "+torrent.MetaInfo)
+
+	torrent.Trusted = isTrustedUploader(torrent.Uploader)
+	verifyMagnet(torrent)
+	torrent.Flags = SuspiciousFlags(*torrent)
+}
+
+func (x *X1337Parser) EnrichTorrents(ctx context.Context, torrents []TorrentFile, opts EnrichOptions) []TorrentFile {
+	return enrichConcurrently(ctx, torrents, opts, x.FetchTorrentDetails)
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// parseFixtureRows runs parseRow on every row of a saved search page
+func parseFixtureRows(t *testing.T, name string, layout listingLayout, parseRow func(s *goquery.Selection) *TorrentFile) []TorrentFile {
+	t.Helper()
+
+	doc, err := loadFixture(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var torrents []TorrentFile
+	doc.Find(layout.Rows).Each(func(i int, s *goquery.Selection) {
+		if torrent := parseRow(s); torrent != nil {
+			torrents = append(torrents, *torrent)
+		}
+	})
+	return torrents
+}
+
+func TestX1337ParseTableRow(t *testing.T) {
+	x := NewX1337Parser("https://1337x.invalid/")
+	torrents := parseFixtureRows(t, "1337x_search.html", x.layout(), x.ParseTableRow)
+
+	want := []TorrentFile{
+		{
+			Name:       "The.Matrix.1999.2160p.UHD.BluRay.x265.HDR.TrueHD.Atmos-TERMiNAL",
+			Href:       "/torrent/5101234/The-Matrix-1999-2160p-UHD-BluRay-x265-HDR-TrueHD-Atmos/",
+			SizeRaw:    "19.8 GB",
+			Size:       19.8,
+			Seeders:    218,
+			Leechers:   34,
+			Uploader:   "TERMiNAL",
+			Category:   "Movies",
+			UploadDate: "Mar. 4th '23",
+		},
+		{
+			Name:       "The Matrix (1999) [1080p] [BluRay] [YTS.MX]",
+			Href:       "/torrent/4101235/The-Matrix-1999-1080p-BluRay-x264-YTS/",
+			SizeRaw:    "2.1 GB",
+			Size:       2.1,
+			Seeders:    934,
+			Leechers:   51,
+			Uploader:   "YTSAgx",
+			Category:   "Movies",
+			UploadDate: "Nov. 19th '21",
+		},
+		{
+			Name:       "The.Matrix.Resurrections.2021.720p.WEB-DL.DDP5.1.H.264-EVO",
+			Href:       "/torrent/3101236/The-Matrix-Resurrections-2021-720p-WEB-DL-DDP5-1/",
+			SizeRaw:    "1.3 GB",
+			Size:       1.3,
+			Seeders:    76,
+			Leechers:   9,
+			Uploader:   "EVO",
+			Category:   "TV",
+			UploadDate: "Jan. 5th '22",
+		},
+	}
+
+	if len(torrents) != len(want) {
+		t.Fatalf("parsed %d rows, want %d", len(torrents), len(want))
+	}
+	for i, w := range want {
+		got := torrents[i]
+		if got.Name != w.Name || got.Href != w.Href || got.SizeRaw != w.SizeRaw || got.Size != w.Size ||
+			got.Seeders != w.Seeders || got.Leechers != w.Leechers || got.Uploader != w.Uploader ||
+			got.Category != w.Category || got.UploadDate != w.UploadDate {
+			t.Errorf("row %d:\n got  %+v\n want %+v", i, got, w)
+		}
+	}
+}
+
+func TestX1337ParseDetails(t *testing.T) {
+	x := NewX1337Parser("https://1337x.invalid/")
+	torrents := parseFixtureRows(t, "1337x_search.html", x.layout(), x.ParseTableRow)
+	if len(torrents) == 0 {
+		t.Fatal("no rows in the search fixture")
+	}
+
+	doc, err := loadFixture("1337x_details.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := torrents[0]
+	x.parseDetails(doc, &torrent)
+
+	wantMagnet := "magnet:?xt=urn:btih:89abcdef0123456789abcdef0123456789abcdef&dn=The.Matrix.1999.2160p.UHD.BluRay.x265.HDR.TrueHD.Atmos-TERMiNAL&tr=udp%3A%2F%2Fopen.stealth.si%3A80%2Fannounce"
+	if torrent.MagnetLink != wantMagnet {
+		t.Errorf("MagnetLink = %q, want %q", torrent.MagnetLink, wantMagnet)
+	}
+	if torrent.InfoHash != "89abcdef0123456789abcdef0123456789abcdef" {
+		t.Errorf("InfoHash = %q", torrent.InfoHash)
+	}
+	if torrent.Category != "Movies" {
+		t.Errorf("Category = %q, want Movies", torrent.Category)
+	}
+	if torrent.Uploader != "TERMiNAL" {
+		t.Errorf("Uploader = %q, want TERMiNAL", torrent.Uploader)
+	}
+	if torrent.Name != "The.Matrix.1999.2160p.UHD.BluRay.x265.HDR.TrueHD.Atmos-TERMiNAL" {
+		t.Errorf("Name changed to %q", torrent.Name)
+	}
+	if torrent.Size != 19.8 || torrent.Seeders != 218 || torrent.Leechers != 34 {
+		t.Errorf("size/seeders/leechers = %v/%d/%d, want 19.8/218/34", torrent.Size, torrent.Seeders, torrent.Leechers)
+	}
+}
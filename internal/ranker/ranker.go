@@ -4,9 +4,13 @@
 package ranker
 
 import (
+	"fmt"
 	"math"
 	"sanjaix21/krakeneye/internal/parser"
+	"sanjaix21/krakeneye/internal/release"
+	"slices"
 	"strings"
+	"time"
 )
 
 /*
@@ -16,21 +20,59 @@ Priority Distribution (based on user requirements):
 1. Seeders (30 points) - Speed of download is crucial
 2. Size (25 points) - Optimal file size for quality
 3. Resolution (20 points) - Video quality matters
-4. Source (15 points) - Source quality (IMAX, Blu-ray, etc.)
+4. Source (15 points) - Source quality (Blu-ray, WEB, etc.)
 5. Codecs (7 points) - Video/Audio codec quality
 6. Uploader Trust (3 points) - Trusted uploaders bonus
 
 Total: 100 points
+
+On top of that HDR adds up to 2 points, or takes up to 10 away for a format the
+user's TV can't play, and the audio preferences add up to 3 points, or take up to
+9 away for stereo or a missing language (see Preferences). Suspicious content
+(executables, archives, sample-only payloads) takes up to 100 points away.
 */
 
+// HDR preferences
+const (
+	HDRAny         = ""      // any HDR is a small bonus over SDR
+	HDRPreferHDR10 = "hdr10" // HDR10/HDR10+ wanted, Dolby Vision without a fallback layer is buried
+	HDRPreferDV    = "dv"    // Dolby Vision wanted, other HDR still beats SDR
+	HDRPreferSDR   = "sdr"   // no HDR capable screen, SDR wins
+)
+
+// Preferences are the user's tastes that change how torrents are scored
+type Preferences struct {
+	HDR      string // one of the HDR* constants
+	Surround bool   // 5.1/7.1 wanted, stereo is pushed down
+	Language string // an audio language that has to be there, e.g. "Hindi"
+
+	HideSuspicious bool // drop flagged torrents instead of just pushing them down
+}
+
+// ParseHDRPreference validates a --hdr flag or ?hdr= value, "any" and "" mean no preference
+func ParseHDRPreference(value string) (string, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "", "any":
+		return HDRAny, nil
+	case HDRPreferHDR10, HDRPreferDV, HDRPreferSDR:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown HDR preference %q, use any, hdr10, dv or sdr", value)
+	}
+}
+
 type RankTorrent struct {
+	Preferences Preferences
+
 	// Storing this mostly for debug purpose
 	SizeScore       float64
 	SeedScore       float64
 	ResolutionScore float64
 	SourceScore     float64
 	CodecsScore     float64
+	HDRScore        float64
 	UploaderScore   float64
+	SuspiciousScore float64
 	TorrentScore    float64
 }
 
@@ -42,7 +84,9 @@ func (rt *RankTorrent) RankTorrentFile(torrent parser.TorrentFile) float64 {
 	torrentScore += rt.RankResolution(torrent)
 	torrentScore += rt.RankSource(torrent)
 	torrentScore += rt.RankCodecs(torrent)
+	torrentScore += rt.RankHDR(torrent)
 	torrentScore += rt.RankUploader(torrent)
+	torrentScore += rt.RankSuspicious(torrent)
 
 	rt.TorrentScore = torrentScore
 	torrent.Score = torrentScore
@@ -130,14 +174,12 @@ func (rt *RankTorrent) RankSeeds(torrent parser.TorrentFile) float64 {
 }
 
 func (rt *RankTorrent) RankSize(torrent parser.TorrentFile) float64 {
-	categoryLower := torrent.Category
-
 	sizeScore := 0.0
-	switch {
-	case strings.Contains(categoryLower, "movies"):
-		sizeScore = rt.rankMovieSize(torrent.Size, torrent.Resolution, torrent.Source)
+	switch parser.NormalizeCategory(torrent.Category) {
+	case parser.CategoryMovies:
+		sizeScore = rt.rankMovieSize(movieSize(torrent), torrent.Resolution, torrent.Source)
 
-	case strings.Contains(categoryLower, "tv"):
+	case parser.CategoryTV:
 		sizeScore = rt.rankTvSize(torrent.Size, torrent.Resolution, torrent.Source)
 
 	default:
@@ -149,6 +191,20 @@ func (rt *RankTorrent) RankSize(torrent parser.TorrentFile) float64 {
 	return sizeScore
 }
 
+// movieSize is the size a two hour cut of the movie would have, the sweet spots assume one.
+// Only a MediaInfo runtime is trusted, and only when it looks like a feature film.
+func movieSize(torrent parser.TorrentFile) float64 {
+	if torrent.MediaInfo == nil {
+		return torrent.Size
+	}
+
+	runtime := torrent.MediaInfo.Runtime
+	if runtime < time.Hour || runtime > 5*time.Hour {
+		return torrent.Size
+	}
+	return torrent.Size * float64(2*time.Hour) / float64(runtime)
+}
+
 // Resolution Ranking (20 Points Max)
 func (rt *RankTorrent) RankResolution(torrent parser.TorrentFile) float64 {
 	resolution := strings.ToUpper(torrent.Resolution)
@@ -177,12 +233,16 @@ func (rt *RankTorrent) RankSource(torrent parser.TorrentFile) float64 {
 	sourceUpper := strings.ToUpper(torrent.Source)
 
 	switch sourceUpper {
-	case "IMAX":
-		sourceScore = 12.0
 	case "BLURAY":
 		sourceScore = 15.0 // most prefer bluray so more score
 	case "WEB":
 		sourceScore = 10.0
+	case "HDTV":
+		sourceScore = 8.0
+	case "DVD":
+		sourceScore = 7.0
+	case "TELESYNC":
+		sourceScore = 6.0
 	case "CAM":
 		sourceScore = 5.0
 	default:
@@ -283,7 +343,121 @@ func (rt *RankTorrent) RankCodecs(torrent parser.TorrentFile) float64 {
 		bitDepthScore = 0.2
 	}
 
-	return videoScore + audioScore + bitDepthScore
+	codecsScore := videoScore + audioScore + bitDepthScore + rt.rankAudioPreferences(torrent)
+	rt.CodecsScore = codecsScore
+	return codecsScore
+}
+
+// rankAudioPreferences scores channels and languages against Preferences (-9 to 3 points)
+func (rt *RankTorrent) rankAudioPreferences(torrent parser.TorrentFile) float64 {
+	var score float64
+
+	if rt.Preferences.Surround {
+		switch torrent.AudioChannels {
+		case "7.1":
+			score += 1.0
+		case "5.1", "6.1":
+			score += 0.8
+		case "":
+			// not named, most movie releases are 5.1 anyway
+		default:
+			score -= 1.0
+		}
+	}
+
+	if language := rt.Preferences.Language; language != "" {
+		switch {
+		case slices.Contains(torrent.AudioLanguages, language):
+			score += 2.0
+		case len(torrent.AudioLanguages) == 0:
+			// can't tell, but a release carrying it would usually say so
+			score -= 2.0
+		default:
+			score -= 8.0
+		}
+	}
+
+	return score
+}
+
+// HDR Ranking (-10 to 2 points), by Preferences.HDR
+func (rt *RankTorrent) RankHDR(torrent parser.TorrentFile) float64 {
+	format := torrent.HDRFormat
+	dolbyVision, _, fallback := release.SplitHDRFormat(format)
+	dolbyVisionOnly := release.DolbyVisionOnly(format)
+
+	var hdrScore float64
+	switch rt.Preferences.HDR {
+	case HDRPreferHDR10:
+		switch {
+		case dolbyVisionOnly:
+			// plays with purple/green colors without a DV capable TV
+			hdrScore = -10.0
+		case fallback == "HDR10+" || fallback == "HDR10":
+			hdrScore = 2.0
+		case format != "":
+			// HLG or an unspecified HDR
+			hdrScore = 1.0
+		}
+
+	case HDRPreferDV:
+		switch {
+		case dolbyVision:
+			hdrScore = 2.0
+		case format != "":
+			hdrScore = 1.0
+		}
+
+	case HDRPreferSDR:
+		switch {
+		case dolbyVisionOnly:
+			hdrScore = -10.0
+		case format != "":
+			// needs tone mapping, washed out colors on most SDR screens
+			hdrScore = -2.0
+		default:
+			hdrScore = 1.0
+		}
+
+	default:
+		switch {
+		case dolbyVisionOnly:
+			hdrScore = 0.5
+		case format != "":
+			hdrScore = 1.0
+		}
+	}
+
+	rt.HDRScore = hdrScore
+	return hdrScore
+}
+
+// Suspicious content penalty (0 to -100 points), an executable sinks any torrent
+func (rt *RankTorrent) RankSuspicious(torrent parser.TorrentFile) float64 {
+	var suspiciousScore float64
+	for _, flag := range torrent.Flags {
+		switch flag {
+		case parser.FlagExecutable:
+			suspiciousScore -= 60.0
+		case parser.FlagPassword, parser.FlagMagnetMismatch:
+			suspiciousScore -= 40.0
+		case parser.FlagSampleOnly:
+			suspiciousScore -= 30.0
+		case parser.FlagArchive:
+			suspiciousScore -= 20.0
+		case parser.FlagSizeMismatch:
+			suspiciousScore -= 10.0
+		}
+	}
+	suspiciousScore = math.Max(suspiciousScore, -100.0)
+
+	rt.SuspiciousScore = suspiciousScore
+	return suspiciousScore
+}
+
+// Hidden reports whether torrent should not be shown at all under the Preferences
+func (rt *RankTorrent) Hidden(torrent parser.TorrentFile) bool {
+	return rt.Preferences.HideSuspicious && len(torrent.Flags) > 0
 }
 
 func (rt *RankTorrent) RankUploader(torrent parser.TorrentFile) float64 {
@@ -401,8 +575,6 @@ func (rt *RankTorrent) getMovieSweetSpot480p(source string) float64 {
 
 func (rt *RankTorrent) getMovieSweetSpot720p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 8.0
 	case strings.Contains(source, "BLURAY"):
 		return 6.0
 	case strings.Contains(source, "WEB"):
@@ -416,8 +588,6 @@ func (rt *RankTorrent) getMovieSweetSpot720p(source string) float64 {
 
 func (rt *RankTorrent) getMovieSweetSpot1080p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 12.0
 	case strings.Contains(source, "BLURAY"):
 		return 10.0
 	case strings.Contains(source, "WEB"):
@@ -431,8 +601,6 @@ func (rt *RankTorrent) getMovieSweetSpot1080p(source string) float64 {
 
 func (rt *RankTorrent) getMovieSweetSpot2160p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 60.0
 	case strings.Contains(source, "BLURAY"):
 		return 50.0
 	case strings.Contains(source, "WEB"):
@@ -457,8 +625,6 @@ func (rt *RankTorrent) getTvSweetSpot480p(source string) float64 {
 
 func (rt *RankTorrent) getTvSweetSpot720p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 12.0
 	case strings.Contains(source, "BLURAY"):
 		return 10.0
 	case strings.Contains(source, "WEB"):
@@ -472,8 +638,6 @@ func (rt *RankTorrent) getTvSweetSpot720p(source string) float64 {
 
 func (rt *RankTorrent) getTvSweetSpot1080p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 55.0
 	case strings.Contains(source, "BLURAY"):
 		return 30.0
 	case strings.Contains(source, "WEB"):
@@ -487,8 +651,6 @@ func (rt *RankTorrent) getTvSweetSpot1080p(source string) float64 {
 
 func (rt *RankTorrent) getTvSweetSpot2160p(source string) float64 {
 	switch {
-	case strings.Contains(source, "IMAX"):
-		return 100.0
 	case strings.Contains(source, "BLURAY"):
 		return 60.0
 	case strings.Contains(source, "WEB"):
@@ -0,0 +1,265 @@
+package ranker
+
+import (
+	"cmp"
+	"math"
+	"sanjaix21/krakeneye/internal/parser"
+	"sanjaix21/krakeneye/internal/release"
+	"slices"
+	"testing"
+)
+
+func TestRankSizeCategoryLabels(t *testing.T) {
+	var rt RankTorrent
+	movie := parser.TorrentFile{Size: 10, Resolution: "1080P", Source: "BLURAY"}
+	want := rt.rankMovieSize(movie.Size, movie.Resolution, movie.Source)
+
+	// every site spells the movie category its own way
+	for _, category := range []string{"Movies", "movies", "MOVIES", "Movies/x264/1080", "Movie"} {
+		movie.Category = category
+		if got := rt.RankSize(movie); got != want {
+			t.Errorf("RankSize with category %q = %v, want the movie score %v", category, got, want)
+		}
+	}
+
+	show := parser.TorrentFile{Size: 1.5, Resolution: "1080P", Source: "WEB"}
+	want = rt.rankTvSize(show.Size, show.Resolution, show.Source)
+	for _, category := range []string{"TV", "tv", "TV/HD"} {
+		show.Category = category
+		if got := rt.RankSize(show); got != want {
+			t.Errorf("RankSize with category %q = %v, want the TV score %v", category, got, want)
+		}
+	}
+}
+
+func TestRankSourceIgnoresEdition(t *testing.T) {
+	var rt RankTorrent
+	parse := func(name string) parser.TorrentFile {
+		info := release.Parse(name)
+		return parser.TorrentFile{Name: name, Source: info.Source, Release: info}
+	}
+
+	// IMAX is an edition, the source of an IMAX release decides its score
+	plain := parse("Dune.2021.1080p.BluRay.x264-GRP")
+	imax := parse("Dune.2021.IMAX.1080p.BluRay.x264-GRP")
+
+	if imax.Release.Edition != "IMAX" || imax.Source != "BLURAY" {
+		t.Fatalf("parsed edition %q source %q, want IMAX and BLURAY", imax.Release.Edition, imax.Source)
+	}
+	if got, want := rt.RankSource(imax), rt.RankSource(plain); got != want {
+		t.Errorf("IMAX BluRay scores %v, plain BluRay %v", got, want)
+	}
+}
+
+func TestRankSuspicious(t *testing.T) {
+	clean := parser.TorrentFile{Name: "The.Matrix.1999.1080p.BluRay.x264-GRP", Category: "Movies", Resolution: "1080P", Source: "BLURAY", Size: 2, Seeders: 150}
+
+	tests := []struct {
+		flags   []string
+		penalty float64
+	}{
+		{nil, 0},
+		{[]string{parser.FlagSizeMismatch}, -10},
+		{[]string{parser.FlagArchive}, -20},
+		{[]string{parser.FlagSampleOnly}, -30},
+		{[]string{parser.FlagPassword}, -40},
+		{[]string{parser.FlagMagnetMismatch}, -40},
+		{[]string{parser.FlagExecutable}, -60},
+		{[]string{parser.FlagArchive, parser.FlagPassword}, -60},
+		{[]string{parser.FlagExecutable, parser.FlagArchive, parser.FlagPassword}, -100},
+	}
+
+	var rt RankTorrent
+	cleanScore := rt.RankTorrentFile(clean)
+	for _, tt := range tests {
+		flagged := clean
+		flagged.Flags = tt.flags
+
+		if got := rt.RankSuspicious(flagged); got != tt.penalty {
+			t.Errorf("RankSuspicious(%v) = %v, want %v", tt.flags, got, tt.penalty)
+		}
+		if got := rt.RankTorrentFile(flagged); got != cleanScore+tt.penalty {
+			t.Errorf("%v scores %v, want %v below the clean %v", tt.flags, got, -tt.penalty, cleanScore)
+		}
+	}
+
+	// an executable sinks even a perfectly seeded release below a poor clean one
+	poor := parser.TorrentFile{Name: "The.Matrix.1999.720p.WEBRip.x264", Category: "Movies", Resolution: "720P", Source: "WEB", Size: 1, Seeders: 3}
+	flagged := clean
+	flagged.Flags = []string{parser.FlagExecutable}
+	if rt.RankTorrentFile(flagged) >= rt.RankTorrentFile(poor) {
+		t.Errorf("flagged %v ranks above poor clean %v", rt.RankTorrentFile(flagged), rt.RankTorrentFile(poor))
+	}
+}
+
+func TestHidden(t *testing.T) {
+	clean := parser.TorrentFile{Name: "clean"}
+	flagged := parser.TorrentFile{Name: "flagged", Flags: []string{parser.FlagSizeMismatch}}
+
+	show := RankTorrent{}
+	if show.Hidden(clean) || show.Hidden(flagged) {
+		t.Error("torrents hidden without HideSuspicious")
+	}
+
+	hide := RankTorrent{Preferences: Preferences{HideSuspicious: true}}
+	if hide.Hidden(clean) {
+		t.Error("a clean torrent was hidden")
+	}
+	if !hide.Hidden(flagged) {
+		t.Error("a flagged torrent was shown with HideSuspicious")
+	}
+}
+
+func TestRankHDR(t *testing.T) {
+	// scores per preference: any, hdr10, dv, sdr
+	tests := []struct {
+		format string
+		want   [4]float64
+	}{
+		{"", [4]float64{0, 0, 0, 1}},
+		{"HDR", [4]float64{1, 1, 1, -2}},
+		{"HDR10", [4]float64{1, 2, 1, -2}},
+		{"HDR10+", [4]float64{1, 2, 1, -2}},
+		{"HLG", [4]float64{1, 1, 1, -2}},
+		{"DV", [4]float64{0.5, -10, 2, -10}},
+		{"DV P5", [4]float64{0.5, -10, 2, -10}},
+		{"DV P7+HDR10", [4]float64{1, 2, 2, -2}},
+		{"DV P8.1+HDR10", [4]float64{1, 2, 2, -2}},
+		{"DV P8.4+HLG", [4]float64{1, 1, 2, -2}},
+		{"DV+HDR10+", [4]float64{1, 2, 2, -2}},
+	}
+	preferences := []string{HDRAny, HDRPreferHDR10, HDRPreferDV, HDRPreferSDR}
+
+	for _, tt := range tests {
+		for i, preference := range preferences {
+			rt := RankTorrent{Preferences: Preferences{HDR: preference}}
+			if got := rt.RankHDR(parser.TorrentFile{HDRFormat: tt.format}); got != tt.want[i] {
+				t.Errorf("RankHDR(%q) preferring %q = %v, want %v", tt.format, preference, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRankHDROrder(t *testing.T) {
+	rank := func(preference string, formats ...string) []string {
+		rt := RankTorrent{Preferences: Preferences{HDR: preference}}
+		var torrents []parser.TorrentFile
+		for _, format := range formats {
+			torrents = append(torrents, parser.TorrentFile{
+				Name: format, Category: "Movies", Resolution: "2160P", Source: "WEB", Size: 15, Seeders: 100, HDRFormat: format,
+			})
+		}
+		for i := range torrents {
+			torrents[i].Score = rt.RankTorrentFile(torrents[i])
+		}
+		slices.SortStableFunc(torrents, func(a, b parser.TorrentFile) int { return cmp.Compare(b.Score, a.Score) })
+
+		var order []string
+		for _, torrent := range torrents {
+			order = append(order, torrent.Name)
+		}
+		return order
+	}
+
+	// a profile 5 release has no HDR10 layer, 7 and 8 play as HDR10 on any HDR TV
+	if got := rank(HDRPreferHDR10, "DV P5", "", "DV P8.1+HDR10", "DV P7+HDR10"); !slices.Equal(got, []string{"DV P8.1+HDR10", "DV P7+HDR10", "", "DV P5"}) {
+		t.Errorf("hdr10 order = %v", got)
+	}
+	if got := rank(HDRPreferDV, "HDR10", "", "DV P5"); !slices.Equal(got, []string{"DV P5", "HDR10", ""}) {
+		t.Errorf("dv order = %v", got)
+	}
+	if got := rank(HDRPreferSDR, "DV P5", "HDR10", ""); !slices.Equal(got, []string{"", "HDR10", "DV P5"}) {
+		t.Errorf("sdr order = %v", got)
+	}
+}
+
+func TestParseHDRPreference(t *testing.T) {
+	for value, want := range map[string]string{"": HDRAny, "any": HDRAny, "HDR10": HDRPreferHDR10, " dv ": HDRPreferDV, "sdr": HDRPreferSDR} {
+		if got, err := ParseHDRPreference(value); err != nil || got != want {
+			t.Errorf("ParseHDRPreference(%q) = %q %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseHDRPreference("hlg"); err == nil {
+		t.Error("ParseHDRPreference accepted hlg")
+	}
+}
+
+func TestRankCodecsAudio(t *testing.T) {
+	var rt RankTorrent
+	codecs := []string{"Dolby Atmos", "DTS-HD/TrueHD", "DTS", "EAC3", "AC3", "AAC", "OPUS", "MP3", "Unknown"}
+
+	previous := 0.0
+	for i, codec := range codecs {
+		score := rt.RankCodecs(parser.TorrentFile{VideoCodec: "x264", BitDepth: "8-bit", AudioCodec: codec})
+		if i > 0 && score >= previous {
+			t.Errorf("%s scores %v, not below %s", codec, score, codecs[i-1])
+		}
+		previous = score
+	}
+}
+
+func TestRankAudioPreferences(t *testing.T) {
+	tests := []struct {
+		preferences Preferences
+		channels    string
+		languages   []string
+		want        float64
+	}{
+		{Preferences{}, "2.0", nil, 0},
+		{Preferences{}, "7.1", []string{"Hindi"}, 0},
+
+		{Preferences{Surround: true}, "7.1", nil, 1},
+		{Preferences{Surround: true}, "5.1", nil, 0.8},
+		{Preferences{Surround: true}, "6.1", nil, 0.8},
+		{Preferences{Surround: true}, "2.0", nil, -1},
+		{Preferences{Surround: true}, "1.0", nil, -1},
+		{Preferences{Surround: true}, "", nil, 0},
+
+		{Preferences{Language: "Hindi"}, "", []string{"English", "Hindi"}, 2},
+		{Preferences{Language: "Hindi"}, "", []string{"English"}, -8},
+		{Preferences{Language: "Hindi"}, "", nil, -2},
+
+		{Preferences{Surround: true, Language: "Hindi"}, "5.1", []string{"Hindi"}, 2.8},
+		{Preferences{Surround: true, Language: "Hindi"}, "2.0", []string{"English"}, -9},
+	}
+
+	for _, tt := range tests {
+		rt := RankTorrent{Preferences: tt.preferences}
+		torrent := parser.TorrentFile{AudioChannels: tt.channels, AudioLanguages: tt.languages}
+		if got := rt.rankAudioPreferences(torrent); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v with %q %v = %v, want %v", tt.preferences, tt.channels, tt.languages, got, tt.want)
+		}
+	}
+}
+
+func TestRankAudioSecondaryTrack(t *testing.T) {
+	parse := func(name string) parser.TorrentFile {
+		info := release.Parse(name)
+		return parser.TorrentFile{
+			Name: name, Category: "Movies", Resolution: info.Resolution, Source: info.Source, Size: 3, Seeders: 100,
+			VideoCodec: info.VideoCodec, AudioCodec: info.AudioCodec, Release: info,
+			AudioChannels: info.WidestChannels(), AudioLanguages: info.Languages,
+		}
+	}
+
+	// the stereo English track comes first, the Hindi 5.1 one second
+	dual := parse("Pathaan.2023.1080p.WEB-DL.English.AAC2.0.Hindi.DD5.1.x264-GRP")
+	single := parse("Pathaan.2023.1080p.WEB-DL.English.AAC2.0.x264-GRP")
+	if dual.AudioChannels != "5.1" || !slices.Contains(dual.AudioLanguages, "Hindi") {
+		t.Fatalf("parsed channels %q languages %v, want the second track counted", dual.AudioChannels, dual.AudioLanguages)
+	}
+
+	rt := RankTorrent{Preferences: Preferences{Surround: true, Language: "Hindi"}}
+	if got := rt.rankAudioPreferences(dual); got != 2.8 {
+		t.Errorf("dual audio release = %v, want 2.8 for the Hindi 5.1 track", got)
+	}
+	if rt.RankTorrentFile(dual) <= rt.RankTorrentFile(single) {
+		t.Errorf("dual audio %v doesn't rank above the English only %v", rt.RankTorrentFile(dual), rt.RankTorrentFile(single))
+	}
+
+	// without preferences the second track earns nothing
+	var plain RankTorrent
+	if got := plain.rankAudioPreferences(dual); got != 0 {
+		t.Errorf("no preferences = %v, want 0", got)
+	}
+}
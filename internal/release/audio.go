@@ -0,0 +1,168 @@
+package release
+
+import "sort"
+
+// AudioTrack is one audio stream a name or description lists, e.g. the "Hindi DD5.1" of
+// "Hindi DD5.1 + English DTS-HD MA 7.1"
+type AudioTrack struct {
+	Codec    string // same values as Info.AudioCodec
+	Channels string // 2.0, 5.1, 7.1 ... empty when not given
+	Language string // empty when not given
+}
+
+var (
+	dualAudioPattern  = word(`dual[ .-]?audio|dual`)
+	multiAudioPattern = word(`multi[ .-]?(?:audio|lang)|multi`)
+
+	// full names and the three letter codes scene names use
+	languageTags = []tag{
+		{"English", word(`english|eng`)},
+		{"Hindi", word(`hindi|hin`)},
+		{"Tamil", word(`tamil|tam`)},
+		{"Telugu", word(`telugu|tel`)},
+		{"Malayalam", word(`malayalam|mal`)},
+		{"Spanish", word(`spanish|spa|esp|castellano|latino`)},
+		{"French", word(`french|fre|fra|truefrench|vff|vf2`)},
+		{"German", word(`german|ger|deu`)},
+		{"Italian", word(`italian|ita`)},
+		{"Portuguese", word(`portuguese|por|pt-?br`)},
+		{"Russian", word(`russian|rus`)},
+		{"Japanese", word(`japanese|jpn|jap`)},
+		{"Korean", word(`korean|kor`)},
+		{"Chinese", word(`chinese|chi|mandarin|cantonese`)},
+		{"Arabic", word(`arabic|ara`)},
+		{"Turkish", word(`turkish|tur`)},
+		{"Polish", word(`polish|pol`)},
+		{"Dutch", word(`dutch|nld`)},
+	}
+)
+
+// NormalizeLanguage turns "hin", "HINDI" or "Hindi" into "Hindi", unknown names come back
+// title cased as given
+func NormalizeLanguage(language string) string {
+	for _, t := range languageTags {
+		if loc := t.pattern.find(language, 0); loc != nil && loc[0] == 0 && loc[1] == len(language) {
+			return t.value
+		}
+	}
+	if language == "" {
+		return ""
+	}
+	return string(toUpper(language[0])) + language[1:]
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+type span struct {
+	start, end int
+	value      string
+}
+
+// findAll returns every proper match of every tag, sorted by position, a match that
+// overlaps an earlier one is dropped (the DTS inside DTS-HD)
+func findAll(s string, tags []tag) []span {
+	var spans []span
+	for _, t := range tags {
+		for loc := t.pattern.find(s, 0); loc != nil; loc = t.pattern.find(s, loc[1]) {
+			spans = append(spans, span{start: loc[0], end: loc[1], value: t.value})
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var kept []span
+	for _, sp := range spans {
+		if len(kept) > 0 && sp.start < kept[len(kept)-1].end {
+			continue
+		}
+		kept = append(kept, sp)
+	}
+	return kept
+}
+
+// parseAudio fills the audio tracks, languages and dual/multi markers found in s
+func (info *Info) parseAudio(s string) {
+	codecs := findAll(s, audioCodecTags)
+	languages := findAll(s, languageTags)
+
+	trackEnd := 0
+	for i := 0; i < len(codecs); i++ {
+		codec := codecs[i]
+		end := codec.end
+
+		// "TrueHD.7.1.Atmos" and "Atmos TrueHD" are one track
+		if i+1 < len(codecs) && codecs[i+1].start-end <= 6 &&
+			(codec.value == "Dolby Atmos" || codecs[i+1].value == "Dolby Atmos") {
+			codec.value = "Dolby Atmos"
+			end = codecs[i+1].end
+			i++
+		}
+
+		track := AudioTrack{Codec: codec.value}
+
+		// channels right after the codec: DDP5.1, DTS-HD MA 7.1, DDP5 1
+		loc := channelsPattern.find(s, codec.end)
+		if loc == nil || loc[0]-codec.end > 4 {
+			loc = spacedChannelsPattern.find(s, codec.end)
+			if loc != nil && loc[0] != codec.end {
+				loc = nil
+			}
+		}
+		if loc != nil {
+			track.Channels = s[loc[2]:loc[3]] + "." + s[loc[4]:loc[5]]
+			end = max(end, loc[1])
+		}
+
+		// a language between the previous track and this one belongs to this one
+		for _, language := range languages {
+			if language.start >= trackEnd && language.end <= codec.start {
+				track.Language = language.value
+			}
+		}
+
+		info.AudioTracks = append(info.AudioTracks, track)
+		trackEnd = end
+	}
+
+	seen := make(map[string]bool)
+	for _, language := range languages {
+		if !seen[language.value] {
+			seen[language.value] = true
+			info.Languages = append(info.Languages, language.value)
+		}
+	}
+
+	info.DualAudio = dualAudioPattern.find(s, 0) != nil
+	info.MultiAudio = multiAudioPattern.find(s, 0) != nil
+}
+
+// TrackCount is how many audio tracks the release has at least: the listed tracks, the
+// named languages, or 2 for a dual/multi audio marker, whichever is most
+func (i Info) TrackCount() int {
+	count := max(len(i.AudioTracks), len(i.Languages))
+	if i.DualAudio || i.MultiAudio {
+		count = max(count, 2)
+	}
+	return count
+}
+
+// WidestChannels is the channel layout of the track with the most channels, "" if none is known
+func (i Info) WidestChannels() string {
+	widest := i.Channels
+	for _, track := range i.AudioTracks {
+		if track.Channels > widest {
+			widest = track.Channels
+		}
+	}
+	return widest
+}
@@ -0,0 +1,93 @@
+package release
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	dolbyVisionPattern = word(`dv|dovi|dolby[ .-]?vision|dvhe|dvh1`)
+
+	// names, plain descriptions and the MediaInfo "HDR format" line
+	hdrTags = []tag{
+		{"HDR10+", word(`hdr10\+|hdr10plus|hdr10p|smpte[ .]?st[ .]?2094(?:[ .]app[ .]?4)?`)},
+		{"HDR10", word(`hdr10|smpte[ .]?st[ .]?2086`)},
+		{"HLG", word(`hlg|arib[ .]?std[ .]?b67`)},
+		{"HDR", word(`hdr`)},
+	}
+
+	// DV P8, DoVi.P7, Dolby Vision Profile 8.1 and MediaInfo's dvhe.08.06
+	dvProfilePatterns = []pattern{
+		word(`(?:dv|dovi|dolby[ .-]?vision)[ .-]?(?:profile[ .-]?|p)(\d)(?:\.(\d))?`),
+		word(`(?:dvhe|dvh1|dvav|dva1)\.0?(\d)\.\d+`),
+		word(`profile[ .-]?(\d)(?:\.(\d))?`),
+	}
+
+	hdrFormatProfile = regexp.MustCompile(`^DV P(\S+?)(?:\+|$)`)
+)
+
+func dvProfile(s string, loc []int) string {
+	profile := s[loc[2]:loc[3]]
+	if len(loc) > 4 && loc[4] >= 0 {
+		profile += "." + s[loc[4]:loc[5]]
+	}
+	return profile
+}
+
+// dvFallback is the layer a Dolby Vision profile carries for players without DV:
+// profile 7 and 8.1 have HDR10, 8.4 has HLG, 5 has none. A bare "8" is nearly always 8.1.
+func dvFallback(profile string) string {
+	switch profile {
+	case "7", "8", "8.1", "8.6":
+		return "HDR10"
+	case "8.4":
+		return "HLG"
+	default:
+		return ""
+	}
+}
+
+// HDRFormat writes the HDR layers as one label: HDR10, HDR10+, HLG, DV, DV P5,
+// DV+HDR10, DV P8.1+HDR10 ... empty for SDR
+func (i Info) HDRFormat() string {
+	if !i.DolbyVision {
+		return i.HDR
+	}
+
+	layer := i.HDR
+	// a plain "HDR" next to DV says nothing about the fallback, the profile does
+	if i.DVProfile != "" && (layer == "" || layer == "HDR") {
+		layer = dvFallback(i.DVProfile)
+	}
+
+	format := "DV"
+	if i.DVProfile != "" {
+		format += " P" + i.DVProfile
+	}
+	if layer != "" {
+		format += "+" + layer
+	}
+	return format
+}
+
+// SplitHDRFormat takes a HDRFormat label apart again
+func SplitHDRFormat(format string) (dolbyVision bool, profile string, fallback string) {
+	if !strings.HasPrefix(format, "DV") {
+		return false, "", format
+	}
+
+	if match := hdrFormatProfile.FindStringSubmatch(format); match != nil {
+		profile = match[1]
+	}
+	if plus := strings.Index(format, "+"); plus >= 0 {
+		fallback = format[plus+1:]
+	}
+	return true, profile, fallback
+}
+
+// DolbyVisionOnly reports whether a HDRFormat label is Dolby Vision without any fallback
+// layer, those play with purple and green colors on TVs without DV
+func DolbyVisionOnly(format string) bool {
+	dolbyVision, _, fallback := SplitHDRFormat(format)
+	return dolbyVision && fallback == ""
+}
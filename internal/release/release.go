@@ -0,0 +1,389 @@
+// Package release reads scene style release names such as
+// The.Matrix.1999.2160p.UHD.BluRay.REMUX.HDR.HEVC.TrueHD.7.1.Atmos-FGT into their parts.
+// Every tag has to stand as a token of its own, so "NF" inside a word or "SD" in a title
+// never count.
+package release
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Info is what a release name says, anything it doesn't mention stays empty
+type Info struct {
+	Title       string
+	Year        int
+	Season      int    // 0 when not a tv release
+	Episode     int    // 0 for movies and season packs
+	Resolution  string // 2160P, 1080P, 720P or 480P
+	Source      string // BLURAY, WEB, HDTV, DVD, TELESYNC or CAM
+	Remux       bool
+	VideoCodec  string // HEVC/x265, AV1, x264, VP9, XviD, VC-1 or MPEG-2
+	AudioCodec  string // Dolby Atmos, DTS-HD/TrueHD, DTS, EAC3, AC3, FLAC, LPCM, AAC, OPUS or MP3
+	Channels    string // of the first track: 2.0, 5.1, 7.1 ...
+	AudioTracks []AudioTrack
+	Languages   []string // every audio language named, in order
+	DualAudio   bool
+	MultiAudio  bool
+	HDR         string // HDR10+, HDR10, HLG or HDR, for Dolby Vision the fallback layer if any
+	DolbyVision bool
+	DVProfile   string // 5, 7, 8.1 ... when the text names it
+	BitDepth    string // 10-bit or 8-bit
+	Container   string // Matroska/MKV or MP4
+	Edition     string // Extended, Director's Cut, IMAX ... joined by spaces
+	Repack      bool
+	Proper      bool
+	Group       string
+}
+
+// pattern is a regexp that only counts when the characters around the match are separators
+type pattern struct {
+	re    *regexp.Regexp
+	left  func(c byte) bool // may not come right before the match
+	right func(c byte) bool // may not come right after the match
+}
+
+// word matches a whole token
+func word(expr string) pattern {
+	return pattern{re: regexp.MustCompile(`(?i)(?:` + expr + `)`), left: isAlnum, right: isAlnum}
+}
+
+// codec is a word that may run straight into its channel count: DDP5.1, AAC2.0
+func codec(expr string) pattern {
+	return pattern{re: regexp.MustCompile(`(?i)(?:` + expr + `)`), left: isAlnum, right: isLetter}
+}
+
+// number only needs to be clear of other digits: DDP5.1 still has a 5.1 in it
+func number(expr string) pattern {
+	return pattern{re: regexp.MustCompile(`(?i)(?:` + expr + `)`), left: isDigit, right: isDigit}
+}
+
+// find returns the submatch indexes of the first proper match in s at or after from
+func (p pattern) find(s string, from int) []int {
+	for from <= len(s) {
+		loc := p.re.FindStringSubmatchIndex(s[from:])
+		if loc == nil {
+			return nil
+		}
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] += from
+			}
+		}
+
+		start, end := loc[0], loc[1]
+		if (start == 0 || !p.left(s[start-1])) && (end == len(s) || !p.right(s[end])) {
+			return loc
+		}
+		from = start + 1
+	}
+	return nil
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isAlnum(c byte) bool  { return isDigit(c) || isLetter(c) }
+
+// tag maps a pattern to the value it stands for, tables are tried in order and the first hit wins
+type tag struct {
+	value   string
+	pattern pattern
+}
+
+var (
+	yearPattern = word(`(?:19|20)\d{2}`)
+
+	// SxxEyy, 1x05, Sxx and Season N, in that order
+	episodePatterns = []pattern{
+		word(`s(\d{1,2})[ .-]?e(\d{1,3})`),
+		word(`(\d{1,2})x(\d{2,3})`),
+		word(`s(\d{1,2})`),
+		word(`season[ .-]?(\d{1,2})`),
+	}
+	// fansub style "Show - 12", an absolute episode number without a season
+	absoluteEpisodePattern = number(` - (\d{1,4})(?:v\d)?`)
+
+	// explicit line counts beat the 4K/UHD marketing labels
+	resolutionTags = []tag{
+		{"2160P", word(`2160[pi]`)},
+		{"1080P", word(`1080[pi]`)},
+		{"720P", word(`720p`)},
+		{"480P", word(`480[pi]|576[pi]`)},
+		{"2160P", word(`4k|uhd`)},
+		{"1080P", word(`fhd`)},
+	}
+
+	sourceTags = []tag{
+		{"CAM", word(`cam|cam-?rip|hd-?cam`)},
+		{"TELESYNC", word(`ts|hd-?ts|telesync|tc|hd-?tc|telecine|pdvd`)},
+		{"BLURAY", word(`blu-?ray|bd-?rip|br-?rip|bd-?remux|bd25|bd50|bdmv`)},
+		{"WEB", word(`web-?dl|web-?rip|web|amzn|nf|dsnp|hmax|atvp|hulu|pcok|pmtp|itunes`)},
+		{"HDTV", word(`hdtv|pdtv|sdtv|dsr|tv-?rip|sat-?rip`)},
+		{"DVD", word(`dvd-?rip|dvd-?r|dvd5|dvd9|dvd|dvd-?scr`)},
+	}
+
+	remuxPattern = word(`remux|bd-?remux`)
+
+	videoCodecTags = []tag{
+		{"HEVC/x265", word(`x265|h[ .]?265|hevc`)},
+		{"AV1", word(`av1`)},
+		{"x264", word(`x264|h[ .]?264|avc`)},
+		{"VP9", word(`vp9`)},
+		{"XviD", word(`xvid|divx`)},
+		{"VC-1", word(`vc-?1`)},
+		{"MPEG-2", word(`mpeg-?2`)},
+	}
+
+	audioCodecTags = []tag{
+		{"Dolby Atmos", codec(`atmos`)},
+		{"DTS-HD/TrueHD", codec(`true-?hd|dts-?hd(?:[ .-]?ma(?:ster[ .]audio)?)?|dts[ :-]?x|dts-?ma`)},
+		{"DTS", codec(`dts`)},
+		{"EAC3", codec(`ddp|dd\+|e-?ac-?3|dolby[ .]digital[ .]plus`)},
+		{"AC3", codec(`dd|ac-?3|dolby[ .]digital`)},
+		{"FLAC", codec(`flac`)},
+		{"LPCM", codec(`l?pcm`)},
+		{"AAC", codec(`aac`)},
+		{"OPUS", codec(`opus`)},
+		{"MP3", codec(`mp3`)},
+	}
+
+	channelsPattern = number(`([1-9])\.([01])`)
+	// "DDP5 1" once the dot became a space, only trusted straight after a codec
+	spacedChannelsPattern = number(`([1-9]) ([01])`)
+
+	bitDepthTags = []tag{
+		{"10-bit", word(`10[ .-]?bits?|hi10p?`)},
+		{"8-bit", word(`8[ .-]?bits?`)},
+	}
+
+	containerTags = []tag{
+		{"Matroska/MKV", word(`mkv|matroska`)},
+		{"MP4", word(`mp4`)},
+	}
+
+	// every edition found is kept
+	editionTags = []tag{
+		{"Extended", word(`extended(?:[ .-]?(?:cut|edition))?`)},
+		{"Director's Cut", word(`directors?'?s?[ .-]?cut`)},
+		{"Theatrical", word(`theatrical(?:[ .-]?cut)?`)},
+		{"Final Cut", word(`final[ .-]?cut`)},
+		{"Unrated", word(`unrated`)},
+		{"Uncut", word(`uncut`)},
+		{"Remastered", word(`remastered`)},
+		{"IMAX", word(`imax`)},
+		{"Criterion", word(`criterion`)},
+		{"Special Edition", word(`special[ .-]?edition`)},
+		{"Anniversary Edition", word(`anniversary[ .-]?edition`)},
+		{"Open Matte", word(`open[ .-]?matte`)},
+	}
+
+	repackPattern = word(`repack\d?|rerip`)
+	properPattern = word(`proper`)
+
+	extensionPattern = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|wmv|ts)$`)
+	leadingGroup     = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+	taggedGroup      = regexp.MustCompile(`-([A-Za-z0-9]+)\s*\[[^\]]*\]$`)
+	bracketGroup     = regexp.MustCompile(`\[([A-Za-z0-9.]+)\]$`)
+	dashGroup        = regexp.MustCompile(`-([A-Za-z0-9]+)$`)
+)
+
+// Parse splits a release name into its parts
+func Parse(name string) Info {
+	var info Info
+
+	name = strings.TrimSpace(name)
+	if loc := extensionPattern.FindStringSubmatchIndex(name); loc != nil {
+		switch strings.ToLower(name[loc[2]:loc[3]]) {
+		case "mkv":
+			info.Container = "Matroska/MKV"
+		case "mp4", "m4v":
+			info.Container = "MP4"
+		}
+		name = name[:loc[0]]
+	}
+
+	name, info.Group = splitGroup(name)
+
+	titleEnd := info.parseHeadline(name)
+	if titleEnd == len(name) {
+		// no year, episode or resolution, the title runs up to the first technical tag
+		titleEnd = info.parseTechnical(name)
+	} else {
+		info.parseTechnical(name[titleEnd:])
+	}
+
+	info.Title = cleanTitle(name[:titleEnd])
+	return info
+}
+
+// ParseDetails reads only the technical tags (resolution, source, codecs, HDR...) from free
+// text such as a torrent description
+func ParseDetails(text string) Info {
+	var info Info
+	info.parseTechnical(text)
+	return info
+}
+
+// splitGroup takes the release group off the name: "[Group] Title", "Title-GROUP[site]",
+// "Title [GROUP]" or "Title-GROUP". A dash only counts once something technical came before
+// it, so "Spider-Man" keeps its "Man".
+func splitGroup(name string) (string, string) {
+	if loc := leadingGroup.FindStringSubmatchIndex(name); loc != nil {
+		return name[loc[1]:], name[loc[2]:loc[3]]
+	}
+
+	for _, re := range []*regexp.Regexp{taggedGroup, bracketGroup, dashGroup} {
+		loc := re.FindStringSubmatchIndex(name)
+		if loc == nil {
+			continue
+		}
+
+		group := name[loc[2]:loc[3]]
+		if isTechnical(group) || firstMarker(name) >= loc[0] {
+			continue
+		}
+		// the X of DTS-X is audio, anywhere else it's a group called X
+		if strings.EqualFold(group, "X") && strings.HasSuffix(strings.ToLower(name[:loc[0]]), "dts") {
+			continue
+		}
+		return strings.TrimSpace(name[:loc[0]]), group
+	}
+
+	return name, ""
+}
+
+// parseHeadline fills year, season and episode and returns where the title ends: at the
+// year, episode or resolution, whichever comes first. It is len(name) when none is there.
+func (info *Info) parseHeadline(name string) int {
+	end := len(name)
+
+	for _, p := range episodePatterns {
+		if loc := p.find(name, 0); loc != nil && loc[0] > 0 {
+			info.Season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+			if len(loc) > 4 && loc[4] >= 0 {
+				info.Episode, _ = strconv.Atoi(name[loc[4]:loc[5]])
+			}
+			end = loc[0]
+			break
+		}
+	}
+	if end == len(name) {
+		if loc := absoluteEpisodePattern.find(name, 0); loc != nil && loc[0] > 0 {
+			info.Episode, _ = strconv.Atoi(name[loc[2]:loc[3]])
+			end = loc[0]
+		}
+	}
+
+	for _, t := range resolutionTags {
+		if loc := t.pattern.find(name, 0); loc != nil && loc[0] > 0 && loc[0] < end {
+			end = loc[0]
+		}
+	}
+
+	// the last year before the end wins (Blade.Runner.2049.2017), one at the very start
+	// belongs to the title (2001.A.Space.Odyssey.1968)
+	var year []int
+	for loc := yearPattern.find(name, 1); loc != nil; loc = yearPattern.find(name, loc[0]+1) {
+		if year != nil && loc[0] >= end {
+			break
+		}
+		year = loc
+	}
+	if year != nil {
+		info.Year, _ = strconv.Atoi(name[year[0]:year[1]])
+		end = min(end, year[0])
+	}
+
+	return end
+}
+
+// parseTechnical fills every technical field found in s and returns where the first
+// tag starts, len(s) when there is none
+func (info *Info) parseTechnical(s string) int {
+	first := len(s)
+	match := func(tags []tag) string {
+		for _, t := range tags {
+			if loc := t.pattern.find(s, 0); loc != nil {
+				first = min(first, loc[0])
+				return t.value
+			}
+		}
+		return ""
+	}
+	has := func(p pattern) bool {
+		loc := p.find(s, 0)
+		if loc != nil {
+			first = min(first, loc[0])
+		}
+		return loc != nil
+	}
+
+	info.Resolution = match(resolutionTags)
+	info.Source = match(sourceTags)
+	info.Remux = has(remuxPattern)
+	if info.Remux && info.Source == "" {
+		info.Source = "BLURAY"
+	}
+	info.VideoCodec = match(videoCodecTags)
+	info.AudioCodec = match(audioCodecTags)
+	if loc := channelsPattern.find(s, 0); loc != nil {
+		info.Channels = s[loc[2]:loc[3]] + "." + s[loc[4]:loc[5]]
+	}
+	info.parseAudio(s)
+	if info.Channels == "" && len(info.AudioTracks) > 0 {
+		info.Channels = info.AudioTracks[0].Channels
+	}
+
+	info.HDR = match(hdrTags)
+	info.DolbyVision = has(dolbyVisionPattern)
+	if info.DolbyVision {
+		for _, p := range dvProfilePatterns {
+			if loc := p.find(s, 0); loc != nil {
+				info.DVProfile = dvProfile(s, loc)
+				break
+			}
+		}
+	}
+
+	info.BitDepth = match(bitDepthTags)
+	if container := match(containerTags); container != "" {
+		info.Container = container
+	}
+
+	var editions []string
+	for _, t := range editionTags {
+		if has(t.pattern) {
+			editions = append(editions, t.value)
+		}
+	}
+	info.Edition = strings.Join(editions, " ")
+
+	info.Repack = has(repackPattern)
+	info.Proper = has(properPattern)
+
+	return first
+}
+
+// firstMarker is where the first year, episode, resolution or technical tag of name starts
+func firstMarker(name string) int {
+	var info Info
+	return min(info.parseHeadline(name), info.parseTechnical(name))
+}
+
+// isTechnical reports whether s is a tag on its own, like the "DL" of a trailing WEB-DL
+func isTechnical(s string) bool {
+	switch strings.ToUpper(s) {
+	case "DL", "HD", "MA", "RIP", "RAY":
+		return true
+	}
+	var info Info
+	return info.parseTechnical(s) == 0
+}
+
+// cleanTitle turns dots and underscores back into spaces and drops leftover brackets
+func cleanTitle(s string) string {
+	s = strings.NewReplacer(".", " ", "_", " ").Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Trim(s, " -([{")
+}
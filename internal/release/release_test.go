@@ -0,0 +1,299 @@
+package release
+
+import "testing"
+
+// parsed is the part of Info the corpus checks, HDR as the HDRFormat label
+type parsed struct {
+	Title      string
+	Year       int
+	Season     int
+	Episode    int
+	Resolution string
+	Source     string
+	Remux      bool
+	VideoCodec string
+	AudioCodec string
+	Channels   string
+	HDR        string
+	Edition    string
+	Repack     bool
+	Proper     bool
+	Group      string
+}
+
+func summarize(info Info) parsed {
+	return parsed{
+		Title:      info.Title,
+		Year:       info.Year,
+		Season:     info.Season,
+		Episode:    info.Episode,
+		Resolution: info.Resolution,
+		Source:     info.Source,
+		Remux:      info.Remux,
+		VideoCodec: info.VideoCodec,
+		AudioCodec: info.AudioCodec,
+		Channels:   info.Channels,
+		HDR:        info.HDRFormat(),
+		Edition:    info.Edition,
+		Repack:     info.Repack,
+		Proper:     info.Proper,
+		Group:      info.Group,
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want parsed
+	}{
+		// the basics
+		{
+			"The.Matrix.1999.2160p.UHD.BluRay.REMUX.HDR.HEVC.TrueHD.7.1.Atmos-FGT",
+			parsed{Title: "The Matrix", Year: 1999, Resolution: "2160P", Source: "BLURAY", Remux: true, VideoCodec: "HEVC/x265", AudioCodec: "Dolby Atmos", Channels: "7.1", HDR: "HDR", Group: "FGT"},
+		},
+		{
+			"Inception.2010.1080p.BluRay.x264.DTS-HD.MA.5.1-SWTYBLZ",
+			parsed{Title: "Inception", Year: 2010, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", AudioCodec: "DTS-HD/TrueHD", Channels: "5.1", Group: "SWTYBLZ"},
+		},
+		{
+			"The Matrix (1999) [1080p] [BluRay] [YTS.MX]",
+			parsed{Title: "The Matrix", Year: 1999, Resolution: "1080P", Source: "BLURAY", Group: "YTS.MX"},
+		},
+		{
+			"Dune.Part.Two.2024.720p.WEBRip.x264.AAC-YTS",
+			parsed{Title: "Dune Part Two", Year: 2024, Resolution: "720P", Source: "WEB", VideoCodec: "x264", AudioCodec: "AAC", Group: "YTS"},
+		},
+
+		// substring traps the old parser fell into
+		{
+			"Infinity.Pool.2023.1080p.BluRay.x264-NOGRP",
+			parsed{Title: "Infinity Pool", Year: 2023, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", Group: "NOGRP"},
+		},
+		{
+			"Webster.1983.S01E01.DVDRip.XviD-SAiNTS",
+			parsed{Title: "Webster", Year: 1983, Season: 1, Episode: 1, Source: "DVD", VideoCodec: "XviD", Group: "SAiNTS"},
+		},
+		{
+			"Cobweb.2023.720p.BluRay.x264-VETO",
+			parsed{Title: "Cobweb", Year: 2023, Resolution: "720P", Source: "BLURAY", VideoCodec: "x264", Group: "VETO"},
+		},
+		{
+			"Sdorica.Sunset.2019.1080p.NF.WEB-DL.DDP5.1.x264-NTb",
+			parsed{Title: "Sdorica Sunset", Year: 2019, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", AudioCodec: "EAC3", Channels: "5.1", Group: "NTb"},
+		},
+		{
+			"Sisu.2022.SDR.1080p.WEB.H264-SLOT",
+			parsed{Title: "Sisu", Year: 2022, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", Group: "SLOT"},
+		},
+		{
+			"Avatar.2009.1080p.BluRay.AVC.DTS-HD.MA.5.1-FGT",
+			parsed{Title: "Avatar", Year: 2009, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", AudioCodec: "DTS-HD/TrueHD", Channels: "5.1", Group: "FGT"},
+		},
+		{
+			"Havoc.2025.2160p.NF.WEB-DL.DDP5.1.Atmos.HEVC-FLUX",
+			parsed{Title: "Havoc", Year: 2025, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "Dolby Atmos", Channels: "5.1", Group: "FLUX"},
+		},
+
+		// spaced channels, common on 1337x
+		{
+			"Oppenheimer 2023 1080p WEB-DL DDP5 1 H 264-FLUX",
+			parsed{Title: "Oppenheimer", Year: 2023, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", AudioCodec: "EAC3", Channels: "5.1", Group: "FLUX"},
+		},
+		{
+			"The Bear S02E03 1080p WEB H264 AAC2 0-GGEZ",
+			parsed{Title: "The Bear", Season: 2, Episode: 3, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", AudioCodec: "AAC", Channels: "2.0", Group: "GGEZ"},
+		},
+
+		// groups
+		{
+			"Twisters.2024.1080p.WEB.h264-X",
+			parsed{Title: "Twisters", Year: 2024, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", Group: "X"},
+		},
+		{
+			"Gladiator.II.2024.2160p.UHD.BluRay.DTS-X",
+			parsed{Title: "Gladiator II", Year: 2024, Resolution: "2160P", Source: "BLURAY", AudioCodec: "DTS-HD/TrueHD"},
+		},
+		{
+			"Mad.Max.Fury.Road.2015.1080p.WEB-DL",
+			parsed{Title: "Mad Max Fury Road", Year: 2015, Resolution: "1080P", Source: "WEB"},
+		},
+		{
+			"[SubsPlease] Frieren - 12 (1080p) [ABCD1234].mkv",
+			parsed{Title: "Frieren", Episode: 12, Resolution: "1080P", Group: "SubsPlease"},
+		},
+
+		// numeric and hyphenated titles
+		{
+			"2001.A.Space.Odyssey.1968.1080p.BluRay.x264-AMIABLE",
+			parsed{Title: "2001 A Space Odyssey", Year: 1968, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", Group: "AMIABLE"},
+		},
+		{
+			"Blade.Runner.2049.2017.2160p.UHD.BluRay.x265-TERMiNAL",
+			parsed{Title: "Blade Runner 2049", Year: 2017, Resolution: "2160P", Source: "BLURAY", VideoCodec: "HEVC/x265", Group: "TERMiNAL"},
+		},
+		{
+			"1917.2019.1080p.BluRay.x264-SPARKS",
+			parsed{Title: "1917", Year: 2019, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", Group: "SPARKS"},
+		},
+		{
+			"Spider-Man.No.Way.Home.2021.1080p.WEBRip.x264-RARBG",
+			parsed{Title: "Spider-Man No Way Home", Year: 2021, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", Group: "RARBG"},
+		},
+		{
+			"Spider-Man Across the Spider-Verse (2023)",
+			parsed{Title: "Spider-Man Across the Spider-Verse", Year: 2023},
+		},
+
+		// repack, proper and editions
+		{
+			"Alien.1979.Directors.Cut.REPACK.1080p.BluRay.x264-AMIABLE",
+			parsed{Title: "Alien", Year: 1979, Resolution: "1080P", Source: "BLURAY", VideoCodec: "x264", Edition: "Director's Cut", Repack: true, Group: "AMIABLE"},
+		},
+		{
+			"The.Office.US.S05E14.PROPER.720p.HDTV.x264-IMMERSE",
+			parsed{Title: "The Office US", Season: 5, Episode: 14, Resolution: "720P", Source: "HDTV", VideoCodec: "x264", Proper: true, Group: "IMMERSE"},
+		},
+		{
+			"Avengers.Infinity.War.2018.IMAX.Extended.1080p.WEB-DL.DD5.1.H264-FGT",
+			parsed{Title: "Avengers Infinity War", Year: 2018, Resolution: "1080P", Source: "WEB", VideoCodec: "x264", AudioCodec: "AC3", Channels: "5.1", Edition: "Extended IMAX", Group: "FGT"},
+		},
+
+		// episodes and season packs
+		{
+			"Breaking.Bad.S01E01.720p.BluRay.x264-DEMAND",
+			parsed{Title: "Breaking Bad", Season: 1, Episode: 1, Resolution: "720P", Source: "BLURAY", VideoCodec: "x264", Group: "DEMAND"},
+		},
+		{
+			"Shogun.2024.S01.2160p.DSNP.WEB-DL.DDP5.1.DV.HDR.H.265-NTb",
+			parsed{Title: "Shogun", Year: 2024, Season: 1, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "EAC3", Channels: "5.1", HDR: "DV+HDR", Group: "NTb"},
+		},
+		{
+			"Friends Season 3 Complete 1080p BluRay x265",
+			parsed{Title: "Friends", Season: 3, Resolution: "1080P", Source: "BLURAY", VideoCodec: "HEVC/x265"},
+		},
+		{
+			"The.Expanse.1x05.HDTV.x264-KILLERS",
+			parsed{Title: "The Expanse", Season: 1, Episode: 5, Source: "HDTV", VideoCodec: "x264", Group: "KILLERS"},
+		},
+
+		// HDR and Dolby Vision
+		{
+			"Dune.2021.2160p.WEB-DL.DDP5.1.Atmos.DV.HDR10.HEVC-FLUX",
+			parsed{Title: "Dune", Year: 2021, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "Dolby Atmos", Channels: "5.1", HDR: "DV+HDR10", Group: "FLUX"},
+		},
+		{
+			"Barbie.2023.2160p.MA.WEB-DL.DDP5.1.Atmos.DV.H.265-FLUX",
+			parsed{Title: "Barbie", Year: 2023, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "Dolby Atmos", Channels: "5.1", HDR: "DV", Group: "FLUX"},
+		},
+		{
+			"Wonka.2023.2160p.UHD.BluRay.REMUX.DV.P7.HDR10.HEVC.TrueHD.7.1.Atmos-FGT",
+			parsed{Title: "Wonka", Year: 2023, Resolution: "2160P", Source: "BLURAY", Remux: true, VideoCodec: "HEVC/x265", AudioCodec: "Dolby Atmos", Channels: "7.1", HDR: "DV P7+HDR10", Group: "FGT"},
+		},
+		{
+			"Loki.S02E01.2160p.DSNP.WEB-DL.DDP5.1.DV.P8.HEVC-NTb",
+			parsed{Title: "Loki", Season: 2, Episode: 1, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "EAC3", Channels: "5.1", HDR: "DV P8+HDR10", Group: "NTb"},
+		},
+		{
+			"The.Boys.S04E01.2160p.AMZN.WEB-DL.DDP5.1.HDR10Plus.H.265-FLUX",
+			parsed{Title: "The Boys", Season: 4, Episode: 1, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "EAC3", Channels: "5.1", HDR: "HDR10+", Group: "FLUX"},
+		},
+		{
+			"Planet.Earth.III.2023.2160p.iP.WEB-DL.AAC2.0.HLG.H.265-playWEB",
+			parsed{Title: "Planet Earth III", Year: 2023, Resolution: "2160P", Source: "WEB", VideoCodec: "HEVC/x265", AudioCodec: "AAC", Channels: "2.0", HDR: "HLG", Group: "playWEB"},
+		},
+
+		// cams and old formats
+		{
+			"Deadpool.and.Wolverine.2024.HDCAM.x264-C1NEM4",
+			parsed{Title: "Deadpool and Wolverine", Year: 2024, Source: "CAM", VideoCodec: "x264", Group: "C1NEM4"},
+		},
+		{
+			"Jaws.1975.480p.DVDRip.XviD.MP3-NoGroup",
+			parsed{Title: "Jaws", Year: 1975, Resolution: "480P", Source: "DVD", VideoCodec: "XviD", AudioCodec: "MP3", Group: "NoGroup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarize(Parse(tt.name)); got != tt.want {
+				t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAudioTracks(t *testing.T) {
+	info := Parse("Jawan.2023.1080p.WEB-DL.Hindi.DD5.1.English.DTS-HD.MA.7.1.x264-Telly")
+
+	want := []AudioTrack{
+		{Codec: "AC3", Channels: "5.1", Language: "Hindi"},
+		{Codec: "DTS-HD/TrueHD", Channels: "7.1", Language: "English"},
+	}
+	if len(info.AudioTracks) != len(want) {
+		t.Fatalf("got %d tracks %+v, want %+v", len(info.AudioTracks), info.AudioTracks, want)
+	}
+	for i := range want {
+		if info.AudioTracks[i] != want[i] {
+			t.Errorf("track %d: got %+v, want %+v", i, info.AudioTracks[i], want[i])
+		}
+	}
+	if info.TrackCount() != 2 || info.WidestChannels() != "7.1" {
+		t.Errorf("TrackCount %d WidestChannels %q, want 2 and 7.1", info.TrackCount(), info.WidestChannels())
+	}
+}
+
+func TestParseDetails(t *testing.T) {
+	info := ParseDetails("Video: AVC, 1920x1080. Audio: English DD+ 5.1, Hindi AAC 2.0. Source: Netflix WEB-DL")
+
+	if info.VideoCodec != "x264" {
+		t.Errorf("VideoCodec = %q, want x264", info.VideoCodec)
+	}
+	if info.AudioCodec != "EAC3" || info.Channels != "5.1" {
+		t.Errorf("audio = %q %q, want EAC3 5.1", info.AudioCodec, info.Channels)
+	}
+	if info.Source != "WEB" {
+		t.Errorf("Source = %q, want WEB", info.Source)
+	}
+
+	// MediaInfo's commercial name spells the codec out
+	if codec := ParseDetails("DTS-HD Master Audio").AudioCodec; codec != "DTS-HD/TrueHD" {
+		t.Errorf("DTS-HD Master Audio = %q, want DTS-HD/TrueHD", codec)
+	}
+}
+
+func TestHDRFormat(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{}, ""},
+		{Info{HDR: "HDR10"}, "HDR10"},
+		{Info{DolbyVision: true}, "DV"},
+		{Info{DolbyVision: true, DVProfile: "5"}, "DV P5"},
+		{Info{DolbyVision: true, DVProfile: "8.4"}, "DV P8.4+HLG"},
+		{Info{DolbyVision: true, DVProfile: "7", HDR: "HDR"}, "DV P7+HDR10"},
+		{Info{DolbyVision: true, HDR: "HDR10+"}, "DV+HDR10+"},
+	}
+
+	for _, tt := range tests {
+		got := tt.info.HDRFormat()
+		if got != tt.want {
+			t.Errorf("HDRFormat(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+		if dv, _, _ := SplitHDRFormat(got); dv != tt.info.DolbyVision {
+			t.Errorf("SplitHDRFormat(%q) dolby vision = %t", got, dv)
+		}
+	}
+
+	if !DolbyVisionOnly("DV P5") || DolbyVisionOnly("DV P8.1+HDR10") || DolbyVisionOnly("HDR10") {
+		t.Error("DolbyVisionOnly misjudges DV P5, DV P8.1+HDR10 or HDR10")
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	for input, want := range map[string]string{"hin": "Hindi", "HINDI": "Hindi", "eng": "English", "klingon": "Klingon", "": ""} {
+		if got := NormalizeLanguage(input); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -1,30 +1,177 @@
 package sites
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"sanjaix21/krakeneye/internal/parser"
+	"slices"
+	"sync"
 	"time"
 )
 
+// MirrorStatus is what probing a mirror found
+type MirrorStatus string
+
+const (
+	MirrorUp           MirrorStatus = "up"
+	MirrorBadStatus    MirrorStatus = "bad status"    // answered, but not with 200
+	MirrorUnreachable  MirrorStatus = "unreachable"   // dns, connect, tls or timeout
+	MirrorChallenge    MirrorStatus = "challenge"     // an anti-bot challenge or interstitial page
+	MirrorWrongContent MirrorStatus = "wrong content" // a parked domain, captive portal or anything the parser can't read
+	MirrorSkipped      MirrorStatus = "skipped"       // failed earlier, not probed again
+	MirrorCircuitOpen  MirrorStatus = "circuit open"  // kept failing, not probed until its cooldown passed
+)
+
 type MirrorResult struct {
-	SiteName string
-	Mirror   string
+	SiteName   string
+	Mirror     string
+	Status     MirrorStatus
+	StatusCode int           // 0 when the mirror never answered
+	Latency    time.Duration // time until the answer (or the failure)
+	Err        error
+	Cached     bool // taken from the MirrorRegistry instead of probed just now
+}
+
+// Healthy reports whether the mirror can be used
+func (r MirrorResult) Healthy() bool {
+	return r.Status == MirrorUp
+}
+
+// a dead mirror should fail fast, so probes never retry
+var probeClient = httpclient.New(httpclient.Options{
+	Timeout:     10 * time.Second,
+	DialTimeout: 5 * time.Second,
+})
+
+// FindFirstWorkingMirror returns the fastest working mirror of the first site, in priority
+// order, that has one. Mirrors in skip are left out.
+func FindFirstWorkingMirror(skip ...string) (*MirrorResult, error) {
+	results, err := FindWorkingMirrors(AllSites(), skip...)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// FindWorkingMirrors probes every mirror of siteList at the same time and returns the fastest
+// working mirror of each site, in siteList order. Sites without one are left out, it only
+// fails when no site has a working mirror.
+func FindWorkingMirrors(siteList []Site, skip ...string) ([]*MirrorResult, error) {
+	results := FastestMirrors(ProbeMirrors(siteList, skip...))
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: no working mirror found for any site", parser.ErrMirrorUnreachable)
+	}
+	return results, nil
+}
+
+// NewAggregator builds the parser of every mirror and searches them all as one. A site
+// whose mirror fails moves on to the next mirror selector picks for it.
+func NewAggregator(results []*MirrorResult, selector parser.MirrorSelector) (*parser.Aggregator, error) {
+	aggregator := parser.NewAggregator()
+	for _, result := range results {
+		torrentParser, err := parser.NewFailoverParser(result.SiteName, result.Mirror, selector)
+		if err != nil {
+			return nil, fmt.Errorf("could not create parser: %w", err)
+		}
+		aggregator.Add(result.SiteName, torrentParser)
+	}
+	return aggregator, nil
+}
+
+// FastestMirror probes the site's mirrors at the same time and returns the fastest working
+// one, mirrors in skip are left out
+func (s Site) FastestMirror(skip ...string) (*MirrorResult, error) {
+	results := FastestMirrors(ProbeMirrors([]Site{s}, skip...))
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: no working mirror found for %s", parser.ErrMirrorUnreachable, s.Name)
+	}
+	return results[0], nil
 }
 
-// tires all mirrors till it find the first working one
-func FindFirstWorkingMirror() (*MirrorResult, error) {
-	for _, site := range PiracySites {
+// ProbeMirrors probes every mirror of siteList at once. The results keep the site and
+// mirror order, mirrors in skip come back as MirrorSkipped.
+func ProbeMirrors(siteList []Site, skip ...string) []MirrorResult {
+	var results []MirrorResult
+	for _, site := range siteList {
 		for _, mirror := range site.Mirrors {
-			client := http.Client{Timeout: 5 * time.Second}
-			resp, err := client.Head(mirror)
-			if err == nil && resp.StatusCode == 200 {
-				return &MirrorResult{
-					SiteName: site.Name,
-					Mirror:   mirror,
-				}, nil
-			}
+			results = append(results, MirrorResult{SiteName: site.Name, Mirror: mirror})
 		}
 	}
 
-	return nil, errors.New("no working mirror found for any site")
+	var wg sync.WaitGroup
+	for i := range results {
+		if slices.Contains(skip, results[i].Mirror) {
+			results[i].Status = MirrorSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeMirror(&results[i])
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// FastestMirrors picks the healthy mirror with the lowest latency of every site in results,
+// in the order the sites first appear
+func FastestMirrors(results []MirrorResult) []*MirrorResult {
+	var fastest []*MirrorResult
+	bySite := make(map[string]int)
+
+	for i := range results {
+		result := &results[i]
+		if !result.Healthy() {
+			continue
+		}
+
+		j, ok := bySite[result.SiteName]
+		if !ok {
+			bySite[result.SiteName] = len(fastest)
+			fastest = append(fastest, result)
+			continue
+		}
+		if result.Latency < fastest[j].Latency {
+			fastest[j] = result
+		}
+	}
+
+	return fastest
+}
+
+// probeMirror fills in the status and latency of result. A mirror is only up when its
+// parser can read what it serves, answering 200 is not enough.
+func probeMirror(result *MirrorResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := parser.VerifyMirror(ctx, probeClient, result.SiteName, result.Mirror)
+	result.Latency = time.Since(start)
+	result.Err = err
+	result.Status, result.StatusCode = mirrorStatus(err)
+}
+
+// mirrorStatus is the status and HTTP status code a request to a mirror that ended in err
+// stands for
+func mirrorStatus(err error) (MirrorStatus, int) {
+	var statusErr *parser.StatusError
+	switch {
+	case err == nil:
+		return MirrorUp, http.StatusOK
+	case errors.Is(err, parser.ErrBlocked):
+		return MirrorChallenge, 0
+	case errors.As(err, &statusErr):
+		return MirrorBadStatus, statusErr.StatusCode
+	case errors.Is(err, parser.ErrMirrorUnreachable), errors.Is(err, context.DeadlineExceeded):
+		return MirrorUnreachable, 0
+	default:
+		return MirrorWrongContent, 0
+	}
 }
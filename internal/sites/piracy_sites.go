@@ -1,5 +1,11 @@
 package sites
 
+import (
+	"os"
+	"sanjaix21/krakeneye/internal/parser"
+	"strings"
+)
+
 type Site struct {
 	Name     string
 	Category string // like torrents/streaming (useful for future updates)
@@ -24,32 +30,83 @@ var PiracySites = []Site{
 		},
 	},
 
-	// {
-	// 	Name:     "https://kickasstorrents.cr/",
-	// 	Category: "torrents",
-	// 	Primary:  "https://kickasstorrents.cr/",
-	// 	Weight:   2,
-	// 	Mirrors: []string{
-	// 		"https://kickasstorrents.cr/",
-	// 		"https://kickasstorrents.cr/",
-	// 		"https://kickass.sx/",
-	// 		"https://katcr.to/",
-	// 		"https://katcr.to/",
-	// 		"https://kickasstorrent.cr/",
-	// 	},
-	// },
-	// {
-	// 	Name:     "1337x",
-	// 	Category: "torrents",
-	// 	Primary:  "https://1337x.to/",
-	// 	Weight:   1,
-	// 	Mirrors: []string{
-	// 		"https://1337x.to/",
-	// 		"https://1337x.st/",
-	// 		"https://x1337x.cc/",
-	// 		"https://x1337x.ws/",
-	// 		"https://x1337x.eu/",
-	// 		"https://x1337x.se/",
-	// 	},
-	// },
+	{
+		Name:     "kickass",
+		Category: "torrents",
+		Primary:  "https://kickasstorrents.cr/",
+		Weight:   2,
+		Mirrors: []string{
+			"https://kickasstorrents.cr/",
+			"https://kickass.sx/",
+			"https://katcr.to/",
+			"https://kickasstorrent.cr/",
+		},
+	},
+	{
+		Name:     "1337x",
+		Category: "torrents",
+		Primary:  "https://1337x.to/",
+		Weight:   1,
+		Mirrors: []string{
+			"https://1337x.to/",
+			"https://1337x.st/",
+			"https://x1337x.cc/",
+			"https://x1337x.ws/",
+			"https://x1337x.eu/",
+			"https://x1337x.se/",
+		},
+	},
+}
+
+// AllSites returns PiracySites followed by any site that only exists as a YAML definition.
+// Definition mirrors for a built-in site are tried after the built-in ones.
+// A Torznab endpoint set in KRAKENEYE_TORZNAB_URL comes before everything else.
+func AllSites() []Site {
+	var allSites []Site
+	if endpoint := os.Getenv("KRAKENEYE_TORZNAB_URL"); endpoint != "" {
+		allSites = append(allSites, Site{
+			Name:     "torznab",
+			Category: "torrents",
+			Primary:  endpoint,
+			Weight:   4,
+			Mirrors:  []string{endpoint},
+		})
+	}
+	allSites = append(allSites, PiracySites...)
+
+	for _, definition := range parser.Definitions(parser.DefinitionsDir) {
+		if len(definition.Mirrors) == 0 {
+			continue
+		}
+
+		known := false
+		for i := range allSites {
+			if strings.EqualFold(allSites[i].Name, definition.Name) {
+				allSites[i].Mirrors = append(append([]string{}, allSites[i].Mirrors...), definition.Mirrors...)
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			allSites = append(allSites, Site{
+				Name:     definition.Name,
+				Category: "torrents",
+				Primary:  definition.Mirrors[0],
+				Mirrors:  definition.Mirrors,
+			})
+		}
+	}
+
+	return allSites
+}
+
+// FindSite returns the site named name from AllSites
+func FindSite(name string) (Site, bool) {
+	for _, site := range AllSites() {
+		if strings.EqualFold(site.Name, name) {
+			return site, true
+		}
+	}
+	return Site{}, false
 }
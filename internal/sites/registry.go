@@ -0,0 +1,358 @@
+package sites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"sanjaix21/krakeneye/internal/parser"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// a site whose picked mirror was checked this recently is not probed again
+	DefaultMirrorTTL = 30 * time.Minute
+	// failures in a row that open a mirror's circuit breaker
+	DefaultFailureThreshold = 3
+	// how long an open breaker keeps a mirror from being probed
+	DefaultCooldown = 30 * time.Minute
+)
+
+// MirrorHealth is what the registry remembers of one mirror
+type MirrorHealth struct {
+	SiteName    string
+	Mirror      string
+	Status      MirrorStatus
+	StatusCode  int
+	Latency     time.Duration
+	Error       string
+	LastChecked time.Time
+	LastSuccess time.Time
+	Failures    int       // in a row, a success resets it
+	OpenUntil   time.Time // the circuit breaker skips the mirror until then
+}
+
+// MirrorRegistry keeps the health of every mirror across runs in a cache file, so a start
+// within the TTL doesn't probe anything and mirrors that keep failing are left alone for a
+// cooldown. It is safe for concurrent use.
+type MirrorRegistry struct {
+	TTL              time.Duration
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu      sync.Mutex
+	path    string // empty keeps the registry in memory
+	mirrors map[string]*MirrorHealth
+}
+
+// DefaultRegistryPath is mirrors.json in krakeneye's folder of the user's cache directory
+// ($XDG_CACHE_HOME or ~/.cache on Linux)
+func DefaultRegistryPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "krakeneye", "mirrors.json"), nil
+}
+
+// OpenMirrorRegistry loads the registry saved at path, a missing file starts an empty one.
+// A file that doesn't parse is ignored with a warning, it gets rewritten on the next Save.
+func OpenMirrorRegistry(path string) (*MirrorRegistry, error) {
+	r := &MirrorRegistry{
+		TTL:              DefaultMirrorTTL,
+		FailureThreshold: DefaultFailureThreshold,
+		Cooldown:         DefaultCooldown,
+		path:             path,
+		mirrors:          make(map[string]*MirrorHealth),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror registry: %w", err)
+	}
+
+	var saved []*MirrorHealth
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("⚠️ Warning: ignoring broken mirror registry %s: %v", path, err)
+		return r, nil
+	}
+	for _, health := range saved {
+		r.mirrors[health.Mirror] = health
+	}
+	return r, nil
+}
+
+// OpenDefaultMirrorRegistry opens the registry at DefaultRegistryPath. When there is no
+// usable cache directory it warns and returns one that lives in memory only.
+func OpenDefaultMirrorRegistry() *MirrorRegistry {
+	path, err := DefaultRegistryPath()
+	if err == nil {
+		var registry *MirrorRegistry
+		if registry, err = OpenMirrorRegistry(path); err == nil {
+			return registry
+		}
+	}
+
+	log.Printf("⚠️ Warning: mirror health won't be remembered: %v", err)
+	registry, _ := OpenMirrorRegistry("")
+	return registry
+}
+
+// Save writes the registry to its cache file
+func (r *MirrorRegistry) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.path == "" {
+		return nil
+	}
+
+	var saved []*MirrorHealth
+	for _, mirror := range slices.Sorted(maps.Keys(r.mirrors)) {
+		saved = append(saved, r.mirrors[mirror])
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode mirror registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save mirror registry: %w", err)
+	}
+	// write then rename, a crash mid write must not leave half a file behind. The temp file
+	// gets a name of its own so two krakeneye runs saving at once can't mix their writes.
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save mirror registry: %w", err)
+	}
+	_, writeErr := tmp.Write(data)
+	if err := errors.Join(writeErr, tmp.Close()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save mirror registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save mirror registry: %w", err)
+	}
+	return nil
+}
+
+// FindWorkingMirrors is the package level FindWorkingMirrors backed by the registry
+func (r *MirrorRegistry) FindWorkingMirrors(siteList []Site, skip ...string) ([]*MirrorResult, error) {
+	results := FastestMirrors(r.Probe(siteList, skip...))
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: no working mirror found for any site", parser.ErrMirrorUnreachable)
+	}
+	return results, nil
+}
+
+// NextMirror returns the fastest working mirror of siteName that is not in failed, it makes
+// the registry the parser.MirrorSelector of a FailoverParser
+func (r *MirrorRegistry) NextMirror(siteName string, failed []string) (string, error) {
+	site, ok := FindSite(siteName)
+	if !ok {
+		return "", fmt.Errorf("unknown site %q", siteName)
+	}
+
+	results, err := r.FindWorkingMirrors([]Site{site}, failed...)
+	if err != nil {
+		return "", fmt.Errorf("%w: no other working mirror for %s", parser.ErrMirrorUnreachable, siteName)
+	}
+	return results[0].Mirror, nil
+}
+
+// Probe returns the health of every mirror of siteList. A site that has a mirror found
+// working within the TTL comes straight from the registry, the others are probed at the same
+// time, except for mirrors in skip and mirrors whose circuit breaker is open. The registry is
+// saved afterwards.
+func (r *MirrorRegistry) Probe(siteList []Site, skip ...string) []MirrorResult {
+	now := time.Now()
+
+	var results []MirrorResult
+	var toProbe []int
+
+	r.mu.Lock()
+	for _, site := range siteList {
+		fresh := false
+		for _, mirror := range site.Mirrors {
+			health, ok := r.mirrors[mirror]
+			if ok && health.Status == MirrorUp && now.Sub(health.LastChecked) < r.TTL && !slices.Contains(skip, mirror) {
+				fresh = true
+			}
+		}
+
+		for _, mirror := range site.Mirrors {
+			result := MirrorResult{SiteName: site.Name, Mirror: mirror}
+			health, known := r.mirrors[mirror]
+
+			switch {
+			case slices.Contains(skip, mirror):
+				result.Status = MirrorSkipped
+			case fresh && known && now.Sub(health.LastChecked) < r.TTL:
+				result = health.result()
+				result.SiteName = site.Name
+			case fresh:
+				result.Status = MirrorSkipped
+			case known && now.Before(health.OpenUntil):
+				result = health.result()
+				result.SiteName = site.Name
+				result.Status = MirrorCircuitOpen
+			default:
+				toProbe = append(toProbe, len(results))
+			}
+			results = append(results, result)
+		}
+	}
+	r.mu.Unlock()
+
+	r.probe(results, toProbe)
+	return results
+}
+
+// Refresh probes every mirror of siteList whatever the registry knows about them
+func (r *MirrorRegistry) Refresh(siteList []Site) []MirrorResult {
+	var results []MirrorResult
+	var toProbe []int
+	for _, site := range siteList {
+		for _, mirror := range site.Mirrors {
+			toProbe = append(toProbe, len(results))
+			results = append(results, MirrorResult{SiteName: site.Name, Mirror: mirror})
+		}
+	}
+
+	r.probe(results, toProbe)
+	return results
+}
+
+// probe runs probeMirror on results[i] for every i in indexes at the same time, records
+// the outcome and saves the registry
+func (r *MirrorRegistry) probe(results []MirrorResult, indexes []int) {
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeMirror(&results[i])
+		}()
+	}
+	wg.Wait()
+
+	for _, i := range indexes {
+		r.Record(results[i])
+	}
+	if len(indexes) > 0 {
+		if err := r.Save(); err != nil {
+			log.Printf("⚠️ Warning: %v", err)
+		}
+	}
+}
+
+// Record stores the outcome of a probe. A failure counts towards the mirror's circuit
+// breaker, a success closes it again.
+func (r *MirrorRegistry) Record(result MirrorResult) {
+	if result.Status == MirrorSkipped || result.Status == MirrorCircuitOpen {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health, ok := r.mirrors[result.Mirror]
+	if !ok {
+		health = &MirrorHealth{SiteName: result.SiteName, Mirror: result.Mirror}
+		r.mirrors[result.Mirror] = health
+	}
+
+	now := time.Now()
+	health.Status = result.Status
+	health.StatusCode = result.StatusCode
+	health.Latency = result.Latency
+	health.LastChecked = now
+	health.Error = ""
+	if result.Err != nil {
+		health.Error = result.Err.Error()
+	}
+
+	if result.Healthy() {
+		health.LastSuccess = now
+		health.Failures = 0
+		health.OpenUntil = time.Time{}
+		return
+	}
+	r.failed(health, now)
+}
+
+// ReportFailure counts a failed request to a mirror that was picked as working, so the
+// next selection doesn't trust the registry's "up" for it. The status is what err says
+// went wrong, the same a probe would have found.
+func (r *MirrorRegistry) ReportFailure(mirror string, err error) {
+	r.mu.Lock()
+	health, ok := r.mirrors[mirror]
+	if ok {
+		now := time.Now()
+		health.Status, health.StatusCode = mirrorStatus(err)
+		if health.Status == MirrorUp {
+			// a failure without an error still isn't a success
+			health.Status = MirrorWrongContent
+		}
+		health.Error = ""
+		if err != nil {
+			health.Error = err.Error()
+		}
+		health.LastChecked = now
+		r.failed(health, now)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		if err := r.Save(); err != nil {
+			log.Printf("⚠️ Warning: %v", err)
+		}
+	}
+}
+
+func (r *MirrorRegistry) failed(health *MirrorHealth, now time.Time) {
+	health.Failures++
+	if health.Failures >= r.FailureThreshold {
+		health.OpenUntil = now.Add(r.Cooldown)
+	}
+}
+
+// Health returns what the registry knows about mirror
+func (r *MirrorRegistry) Health(mirror string) (MirrorHealth, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health, ok := r.mirrors[mirror]
+	if !ok {
+		return MirrorHealth{}, false
+	}
+	return *health, true
+}
+
+// result turns a remembered health back into a probe result
+func (h *MirrorHealth) result() MirrorResult {
+	result := MirrorResult{
+		SiteName:   h.SiteName,
+		Mirror:     h.Mirror,
+		Status:     h.Status,
+		StatusCode: h.StatusCode,
+		Latency:    h.Latency,
+		Cached:     true,
+	}
+	if h.Error != "" {
+		result.Err = errors.New(h.Error)
+	}
+	return result
+}
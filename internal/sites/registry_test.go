@@ -0,0 +1,203 @@
+package sites
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sanjaix21/krakeneye/internal/parser"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mirrorServer serves the saved 1337x search page while up is true and 503 otherwise, and
+// counts the requests it got
+type mirrorServer struct {
+	*httptest.Server
+	up       atomic.Bool
+	requests atomic.Int32
+}
+
+func newMirrorServer(t *testing.T, up bool) *mirrorServer {
+	t.Helper()
+
+	page, err := os.ReadFile("../parser/fixtures/1337x_search.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mirrorServer{}
+	m.up.Store(up)
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.requests.Add(1)
+		if !m.up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(page)
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mirrorServer) mirror() string {
+	return m.URL + "/"
+}
+
+func testSite(mirrors ...*mirrorServer) Site {
+	site := Site{Name: "1337x"}
+	for _, m := range mirrors {
+		site.Mirrors = append(site.Mirrors, m.mirror())
+	}
+	return site
+}
+
+func TestRegistryReusesWithinTTL(t *testing.T) {
+	server := newMirrorServer(t, true)
+	registry, _ := OpenMirrorRegistry("")
+	site := testSite(server)
+
+	results := registry.Probe([]Site{site})
+	if len(results) != 1 || !results[0].Healthy() || results[0].Cached {
+		t.Fatalf("first probe = %+v, want a fresh healthy result", results)
+	}
+
+	results = registry.Probe([]Site{site})
+	if !results[0].Healthy() || !results[0].Cached {
+		t.Errorf("second probe = %+v, want the cached result", results[0])
+	}
+	if got := server.requests.Load(); got != 1 {
+		t.Errorf("%d requests, the second probe should have come from the registry", got)
+	}
+
+	registry.TTL = 0
+	if results = registry.Probe([]Site{site}); results[0].Cached || server.requests.Load() != 2 {
+		t.Errorf("probe after the TTL = %+v after %d requests, want a new probe", results[0], server.requests.Load())
+	}
+}
+
+func TestRegistryBreakerOpens(t *testing.T) {
+	server := newMirrorServer(t, false)
+	registry, _ := OpenMirrorRegistry("")
+	site := testSite(server)
+
+	for i := 1; i <= DefaultFailureThreshold; i++ {
+		results := registry.Probe([]Site{site})
+		if results[0].Status != MirrorBadStatus || results[0].StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("probe %d = %+v, want bad status 503", i, results[0])
+		}
+	}
+
+	health, _ := registry.Health(server.mirror())
+	if health.Failures != DefaultFailureThreshold || !health.OpenUntil.After(time.Now()) {
+		t.Fatalf("health = %+v, want the breaker open after %d failures", health, DefaultFailureThreshold)
+	}
+
+	results := registry.Probe([]Site{site})
+	if results[0].Status != MirrorCircuitOpen {
+		t.Errorf("probe with an open breaker = %+v, want circuit open", results[0])
+	}
+	if got := server.requests.Load(); got != DefaultFailureThreshold {
+		t.Errorf("%d requests, an open breaker must not probe", got)
+	}
+
+	if _, err := registry.FindWorkingMirrors([]Site{site}); err == nil {
+		t.Error("FindWorkingMirrors picked a mirror with an open breaker")
+	}
+}
+
+func TestRegistryCooldown(t *testing.T) {
+	server := newMirrorServer(t, false)
+	registry, _ := OpenMirrorRegistry("")
+	registry.FailureThreshold = 1
+	registry.Cooldown = 50 * time.Millisecond
+	site := testSite(server)
+
+	registry.Probe([]Site{site})
+	if results := registry.Probe([]Site{site}); results[0].Status != MirrorCircuitOpen {
+		t.Fatalf("probe during the cooldown = %+v, want circuit open", results[0])
+	}
+
+	time.Sleep(registry.Cooldown)
+	server.up.Store(true)
+
+	results := registry.Probe([]Site{site})
+	if !results[0].Healthy() {
+		t.Fatalf("probe after the cooldown = %+v, want the recovered mirror up", results[0])
+	}
+	health, _ := registry.Health(server.mirror())
+	if health.Failures != 0 || !health.OpenUntil.IsZero() {
+		t.Errorf("health = %+v, a success should close the breaker", health)
+	}
+}
+
+func TestRegistryReportFailure(t *testing.T) {
+	registry, _ := OpenMirrorRegistry("")
+	mirror := "https://mirror.invalid/"
+	registry.Record(MirrorResult{SiteName: "1337x", Mirror: mirror, Status: MirrorUp})
+
+	tests := []struct {
+		err    error
+		status MirrorStatus
+		code   int
+	}{
+		{fmt.Errorf("search failed: %w", parser.ErrBlocked), MirrorChallenge, 0},
+		{&parser.StatusError{URL: mirror, StatusCode: http.StatusBadGateway}, MirrorBadStatus, http.StatusBadGateway},
+		{fmt.Errorf("%w: connection refused", parser.ErrMirrorUnreachable), MirrorUnreachable, 0},
+		{fmt.Errorf("%w: parked domain", parser.ErrNotSite), MirrorWrongContent, 0},
+	}
+	for i, tt := range tests {
+		registry.ReportFailure(mirror, tt.err)
+
+		health, _ := registry.Health(mirror)
+		if health.Status != tt.status || health.StatusCode != tt.code || health.Error != tt.err.Error() {
+			t.Errorf("after %v: status %q code %d error %q, want %q %d", tt.err, health.Status, health.StatusCode, health.Error, tt.status, tt.code)
+		}
+		if health.Failures != i+1 {
+			t.Errorf("after %v: %d failures, want %d", tt.err, health.Failures, i+1)
+		}
+	}
+}
+
+func TestRegistrySaveAndOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "krakeneye", "mirrors.json")
+
+	registry, err := OpenMirrorRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Record(MirrorResult{SiteName: "1337x", Mirror: "https://a.invalid/", Status: MirrorUp, Latency: time.Second})
+	registry.Record(MirrorResult{SiteName: "1337x", Mirror: "https://b.invalid/", Status: MirrorChallenge})
+	if err := registry.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 || entries[0].Name() != "mirrors.json" {
+		t.Errorf("cache directory holds %v, want only mirrors.json", entries)
+	}
+
+	reopened, err := OpenMirrorRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health, ok := reopened.Health("https://a.invalid/"); !ok || health.Status != MirrorUp || health.Latency != time.Second {
+		t.Errorf("reopened a = %+v %t", health, ok)
+	}
+	if health, ok := reopened.Health("https://b.invalid/"); !ok || health.Status != MirrorChallenge || health.Failures != 1 {
+		t.Errorf("reopened b = %+v %t", health, ok)
+	}
+
+	// a broken file starts over instead of failing
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if broken, err := OpenMirrorRegistry(path); err != nil {
+		t.Errorf("broken registry: %v", err)
+	} else if _, ok := broken.Health("https://a.invalid/"); ok {
+		t.Error("a broken registry file still gave health")
+	}
+}
@@ -0,0 +1,89 @@
+package webui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sanjaix21/krakeneye/internal/metainfo"
+	"sanjaix21/krakeneye/internal/parser"
+	"strings"
+	"sync"
+	"time"
+)
+
+// downloadLinks remembers the .torrent URL of every result handed out, keyed by info-hash.
+// /download only fetches URLs from here, so it can't be used as an open proxy.
+type downloadLinks struct {
+	mu   sync.Mutex
+	urls map[string]string
+}
+
+// a long running server forgets everything once it has seen this many links
+const maxDownloadLinks = 10000
+
+func newDownloadLinks() *downloadLinks {
+	return &downloadLinks{urls: make(map[string]string)}
+}
+
+// remember stores the .torrent URL of torrent, if it has one and an info-hash to check it against
+func (d *downloadLinks) remember(torrent parser.TorrentFile) {
+	if torrent.TorrentURL == "" || torrent.InfoHash == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.urls) >= maxDownloadLinks {
+		d.urls = make(map[string]string)
+	}
+	d.urls[torrent.InfoHash] = torrent.TorrentURL
+}
+
+func (d *downloadLinks) lookup(infoHash string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	torrentURL, ok := d.urls[infoHash]
+	return torrentURL, ok
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._ \[\]()-]+`)
+
+// downloadHandler serves /download?hash=<infohash>: it fetches the result's .torrent,
+// checks that it is the torrent the result promised and hands it to the browser
+func downloadHandler(links *downloadLinks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		infoHash := strings.ToLower(r.URL.Query().Get("hash"))
+		torrentURL, ok := links.lookup(infoHash)
+		if !ok {
+			http.Error(w, "unknown torrent, search for it first", http.StatusNotFound)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		torrent, data, err := metainfo.Fetch(ctx, torrentURL)
+		if err != nil {
+			log.Printf("⚠️ .torrent download failed: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if torrent.InfoHash != infoHash && torrent.InfoHashV2 != infoHash {
+			http.Error(w, fmt.Sprintf("the site served another torrent (%s)", torrent.InfoHash), http.StatusBadGateway)
+			return
+		}
+
+		fileName := strings.TrimSpace(unsafeFileChars.ReplaceAllString(torrent.Name, "_"))
+		if fileName == "" {
+			fileName = infoHash
+		}
+
+		w.Header().Set("Content-Type", "application/x-bittorrent")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.torrent"`, fileName))
+		w.Write(data)
+	}
+}
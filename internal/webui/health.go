@@ -0,0 +1,40 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"sanjaix21/krakeneye/internal/parser"
+)
+
+// healthHandler serves /api/health/parsers: the fixture checks of every built-in parser
+// plus a live check of every mirror in use, skipped with ?offline=1. Any failing check
+// turns the answer into a 503 so it can back a monitoring probe.
+func healthHandler(aggregator *parser.Aggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reports := parser.CheckFixtures()
+
+		if r.URL.Query().Get("offline") != "1" {
+			query := r.URL.Query().Get("q")
+			if query == "" {
+				query = parser.DefaultHealthQuery
+			}
+			for _, siteName := range aggregator.Sites() {
+				torrentParser, _ := aggregator.Parser(siteName)
+				if failover, ok := torrentParser.(*parser.FailoverParser); ok {
+					reports = append(reports, parser.CheckLive(r.Context(), siteName, failover.Mirror(), query))
+				}
+			}
+		}
+
+		status := http.StatusOK
+		for _, report := range reports {
+			if !report.Healthy {
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(reports)
+	}
+}
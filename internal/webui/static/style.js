@@ -1,5 +1,10 @@
 function searchTorrents() {
   const query = document.getElementById("searchInput").value.trim();
+  const category = document.getElementById("categorySelect").value;
+  const hdr = document.getElementById("hdrSelect").value;
+  const surround = document.getElementById("surroundSelect").value;
+  const lang = document.getElementById("langInput").value.trim();
+  const safe = document.getElementById("safeSelect").value;
   const loading = document.getElementById("loading");
   const results = document.getElementById("results");
 
@@ -8,12 +13,16 @@ function searchTorrents() {
   results.innerHTML = "";
   loading.classList.remove("hidden");
 
-  fetch(`/search?q=${encodeURIComponent(query)}`)
-    .then(res => res.json())
+  fetch(`/search?q=${encodeURIComponent(query)}&cat=${encodeURIComponent(category)}&hdr=${encodeURIComponent(hdr)}&surround=${surround}&lang=${encodeURIComponent(lang)}&safe=${safe}`)
+    .then(async res => {
+      if (res.status === 404) return [];
+      if (!res.ok) throw new Error((await res.text()).trim() || res.statusText);
+      return res.json();
+    })
     .then(data => {
       loading.classList.add("hidden");
 
-      if (!data.length) {
+      if (!data || !data.length) {
         results.innerHTML = "<p class='text-center text-red-500'>⚠️ No results found</p>";
         return;
       }
@@ -21,23 +30,51 @@ function searchTorrents() {
       results.innerHTML = data.map(t => `
         <div class="bg-gradient-to-br from-gray-900 to-red-950 p-4 rounded-2xl shadow-lg border border-red-700 transition-transform hover:scale-105 duration-200 overflow-hidden">
         <h2 class="text-xl font-bold text-yellow-300 break-words mb-2">${t.Name}</h2>
+        ${t.Flags?.length ? `<p class="text-red-400 font-bold mb-2">⚠️ Suspicious: ${t.Flags.join(", ")}</p>` : ""}
         <div class="text-sm text-gray-300 space-y-1">
           <p>🎬 <span class="text-white">Size:</span> ${t.Size || "?"}</p>
           <p>📺 <span class="text-white">Resolution:</span> ${t.Resolution || "Unknown"}</p>
+          <p>🔆 <span class="text-white">HDR:</span> ${t.HDRFormat || "SDR"}</p>
+          <p>🎧 <span class="text-white">Audio:</span> ${audioSummary(t)}</p>
+          ${t.Files?.length ? `<p>🗂️ <span class="text-white">Files:</span> ${t.Files.length}</p>` : ""}
+          ${t.MediaInfo ? `<p>🔬 <span class="text-white">MediaInfo:</span> ${mediaInfoSummary(t.MediaInfo)}</p>` : ""}
           <p>🌱 <span class="text-white">Seeders:</span> ${t.Seeders || "?"}</p>
-          <p>🧭 <span class="text-white">Source:</span> ${t.SiteName || "Unknown"}</p>
-          <p>🧲 <button onclick='copyMagnet("${t.MagnetLink}")' class="mt-1 bg-red-600 hover:bg-red-500 px-3 py-1 rounded-full text-white font-bold">Magnet Link</button></p>
+          <p>🧭 <span class="text-white">Source:</span> ${t.Sources?.length ? t.Sources.join(", ") : t.SiteName || "Unknown"}${t.Mirror ? ` <span class="text-xs text-gray-500">via ${t.Mirror}</span>` : ""}</p>
+          <p>🧲 <button onclick='copyMagnet("${t.MagnetLink}")' class="mt-1 bg-red-600 hover:bg-red-500 px-3 py-1 rounded-full text-white font-bold">Magnet Link</button>
+            ${t.TorrentURL && t.InfoHash ? `<a href="/download?hash=${t.InfoHash}" class="inline-block mt-1 bg-gray-700 hover:bg-gray-600 px-3 py-1 rounded-full text-white font-bold">Download .torrent</a>` : ""}
+          </p>
           <p class="text-right text-xs text-red-400 italic">🐉 KrakenEye Score: ${t.Score?.toFixed(2)}</p>
         </div>
       </div>
   `).join("");
     })
-    .catch(() => {
+    .catch(err => {
       loading.classList.add("hidden");
-      results.innerHTML = "<p class='text-center text-red-500'>⚠️ Error fetching results</p>";
+      results.innerHTML = `<p class='text-center text-red-500'>⚠️ Error fetching results: ${err.message}</p>`;
     });
 }
 
+function audioSummary(t) {
+  const parts = [t.AudioCodec && t.AudioCodec !== "Unknown" ? t.AudioCodec : "?"];
+  if (t.AudioChannels) parts.push(t.AudioChannels);
+  if (t.AudioTracks > 1) parts.push(`${t.AudioTracks} tracks`);
+  if (t.AudioLanguages?.length) parts.push(t.AudioLanguages.join(", "));
+  return parts.join(" · ");
+}
+
+// Runtime comes as nanoseconds, bitrates as kb/s
+function mediaInfoSummary(m) {
+  const v = m.Video;
+  const parts = [];
+  if (v.Width) parts.push(`${v.Width}x${v.Height}`);
+  if (v.FrameRate) parts.push(`${v.FrameRate} fps`);
+  if (v.Bitrate) parts.push(`${(v.Bitrate / 1000).toFixed(1)} Mb/s`);
+  if (m.Runtime) parts.push(`${Math.round(m.Runtime / 6e10)} min`);
+  const subs = [...new Set((m.Subtitles || []).map(s => s.Language).filter(Boolean))];
+  if (subs.length) parts.push(`subs: ${subs.join(", ")}`);
+  return parts.join(" · ");
+}
+
 function copyMagnet(link) {
   navigator.clipboard.writeText(link);
   alert("🧲 Magnet link copied!");
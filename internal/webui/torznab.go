@@ -0,0 +1,354 @@
+package webui
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sanjaix21/krakeneye/internal/magnet"
+	"sanjaix21/krakeneye/internal/parser"
+	"sanjaix21/krakeneye/internal/ranker"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Torznab error codes from the newznab spec
+const (
+	torznabErrBadAPIKey      = 100
+	torznabErrMissingParam   = 200
+	torznabErrNoSuchFunction = 202
+	torznabErrUnknown        = 900
+)
+
+const (
+	torznabNamespace          = "http://torznab.com/schemas/2015/feed"
+	torznabAtomNamespace      = "http://www.w3.org/2005/Atom"
+	torznabServerTitle        = "KrakenEye"
+	torznabResultLimitDefault = 100
+	torznabDefaultCategory    = 8000
+	torznabAnimeCategory      = 5070
+	torznabBytesPerGigabyte   = 1024 * 1024 * 1024
+)
+
+// top level newznab categories KrakenEye can tell apart
+var torznabCategories = []torznabCapsCategory{
+	{ID: 1000, Name: "Games"},
+	{ID: 2000, Name: "Movies"},
+	{ID: 3000, Name: "Music"},
+	{ID: 4000, Name: "Apps"},
+	{ID: 5000, Name: "TV", Subcats: []torznabCapsCategory{{ID: torznabAnimeCategory, Name: "Anime"}}},
+	{ID: 6000, Name: "XXX"},
+	{ID: 7000, Name: "Books"},
+	{ID: 8000, Name: "Other"},
+}
+
+type torznabCaps struct {
+	XMLName xml.Name `xml:"caps"`
+	Server  struct {
+		Title string `xml:"title,attr"`
+	} `xml:"server"`
+	Limits struct {
+		Max     int `xml:"max,attr"`
+		Default int `xml:"default,attr"`
+	} `xml:"limits"`
+	Searching struct {
+		Search      torznabSearchCap `xml:"search"`
+		TVSearch    torznabSearchCap `xml:"tv-search"`
+		MovieSearch torznabSearchCap `xml:"movie-search"`
+	} `xml:"searching"`
+	Categories []torznabCapsCategory `xml:"categories>category"`
+}
+
+type torznabSearchCap struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type torznabCapsCategory struct {
+	ID      int                   `xml:"id,attr"`
+	Name    string                `xml:"name,attr"`
+	Subcats []torznabCapsCategory `xml:"subcat"`
+}
+
+type torznabRSS struct {
+	XMLName   xml.Name       `xml:"rss"`
+	Version   string         `xml:"version,attr"`
+	AtomNS    string         `xml:"xmlns:atom,attr"`
+	TorznabNS string         `xml:"xmlns:torznab,attr"`
+	Channel   torznabChannel `xml:"channel"`
+}
+
+type torznabChannel struct {
+	Title       string           `xml:"title"`
+	Description string           `xml:"description"`
+	Items       []torznabRSSItem `xml:"item"`
+}
+
+type torznabRSSItem struct {
+	Title     string `xml:"title"`
+	GUID      string `xml:"guid"`
+	Link      string `xml:"link"`
+	Comments  string `xml:"comments,omitempty"`
+	Size      int64  `xml:"size"`
+	Category  int    `xml:"category"`
+	Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	Attrs []torznabRSSAttr `xml:"torznab:attr"`
+}
+
+type torznabRSSAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabErrorReply struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+// torznabHandler serves /api/torznab so Sonarr/Radarr can use KrakenEye as an indexer.
+// Set KRAKENEYE_APIKEY to require an apikey from clients.
+func torznabHandler(torrentParser parser.TorrentParser, trackers magnet.TrackerList) http.HandlerFunc {
+	apiKey := os.Getenv("KRAKENEYE_APIKEY")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		if apiKey != "" && params.Get("apikey") != apiKey {
+			writeTorznabError(w, torznabErrBadAPIKey, "Incorrect user credentials")
+			return
+		}
+
+		switch params.Get("t") {
+		case "caps":
+			writeTorznabXML(w, buildTorznabCaps())
+
+		case "search", "tvsearch", "movie":
+			query := buildTorznabQuery(params.Get("t"), params)
+
+			// an id alone can't be searched on the sites, the caps don't offer imdbid
+			if query == "" && params.Get("imdbid") != "" {
+				writeTorznabXML(w, buildTorznabFeed(nil, torznabResultLimitDefault))
+				return
+			}
+
+			// *arr clients test a new indexer and sync rss with an empty query. The sites have
+			// no "latest" feed, this year's releases are the closest thing to one.
+			if query == "" {
+				query = strconv.Itoa(time.Now().Year())
+			}
+
+			opts := parser.DefaultSearchOptions()
+			if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+				opts.MaxResults = limit
+			}
+			opts.Category = torznabSearchCategory(params.Get("t"), params.Get("cat"))
+			if opts.MaxResults <= 0 || opts.MaxResults > torznabResultLimitDefault {
+				opts.MaxResults = torznabResultLimitDefault
+			}
+
+			torrents, err := searchAndRank(r.Context(), torrentParser, query, opts, parser.DefaultEnrichOptions(), ranker.Preferences{}, trackers)
+			if r.Context().Err() != nil {
+				return
+			}
+			if err != nil && !errors.Is(err, parser.ErrNoResults) {
+				log.Printf("⚠️ torznab search for %q failed: %v", query, err)
+				writeTorznabError(w, torznabErrUnknown, "Search failed")
+				return
+			}
+
+			writeTorznabXML(w, buildTorznabFeed(torrents, opts.MaxResults))
+
+		case "":
+			writeTorznabError(w, torznabErrMissingParam, "Missing parameter (t)")
+
+		default:
+			writeTorznabError(w, torznabErrNoSuchFunction, "No such function ("+params.Get("t")+")")
+		}
+	}
+}
+
+// buildTorznabQuery turns the function specific params into a plain text query
+func buildTorznabQuery(function string, params map[string][]string) string {
+	get := func(key string) string {
+		if values := params[key]; len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+		return ""
+	}
+
+	query := get("q")
+
+	switch function {
+	case "tvsearch":
+		season, seasonErr := strconv.Atoi(get("season"))
+		episode, episodeErr := strconv.Atoi(get("ep"))
+		switch {
+		case seasonErr == nil && episodeErr == nil:
+			query = fmt.Sprintf("%s S%02dE%02d", query, season, episode)
+		case seasonErr == nil:
+			query = fmt.Sprintf("%s S%02d", query, season)
+		}
+	}
+
+	return strings.TrimSpace(query)
+}
+
+// torznabSearchCategory picks the search category from the function, or from the first
+// top level newznab id in cat (e.g. "2000,2040")
+func torznabSearchCategory(function string, cat string) string {
+	switch function {
+	case "movie":
+		return parser.CategoryMovies
+	case "tvsearch":
+		return parser.CategoryTV
+	}
+
+	for _, id := range strings.Split(cat, ",") {
+		// ids above 9999 are indexer specific
+		if id = strings.TrimSpace(id); len(id) != 4 {
+			continue
+		}
+
+		switch id[0] {
+		case '1':
+			return parser.CategoryGames
+		case '2':
+			return parser.CategoryMovies
+		case '3':
+			return parser.CategoryMusic
+		case '5':
+			return parser.CategoryTV
+		}
+	}
+
+	return parser.CategoryAll
+}
+
+func buildTorznabCaps() torznabCaps {
+	caps := torznabCaps{}
+	caps.Server.Title = torznabServerTitle
+	caps.Limits.Max = torznabResultLimitDefault
+	caps.Limits.Default = torznabResultLimitDefault
+	caps.Searching.Search = torznabSearchCap{Available: "yes", SupportedParams: "q"}
+	caps.Searching.TVSearch = torznabSearchCap{Available: "yes", SupportedParams: "q,season,ep"}
+	// the sites only search names, without imdbid in the caps Radarr sends the title
+	caps.Searching.MovieSearch = torznabSearchCap{Available: "yes", SupportedParams: "q"}
+	caps.Categories = torznabCategories
+
+	return caps
+}
+
+func buildTorznabFeed(torrents []*parser.TorrentFile, limit int) torznabRSS {
+	feed := torznabRSS{
+		Version:   "2.0",
+		AtomNS:    torznabAtomNamespace,
+		TorznabNS: torznabNamespace,
+	}
+	feed.Channel.Title = torznabServerTitle
+	feed.Channel.Description = "KrakenEye ranked torrent results"
+
+	// best ranked first, *arr clients only look at what they get
+	sort.SliceStable(torrents, func(i, j int) bool {
+		return torrents[i].Score > torrents[j].Score
+	})
+
+	for _, torrent := range torrents {
+		if len(feed.Channel.Items) >= limit {
+			break
+		}
+		// without a magnet there is nothing for the client to grab
+		if torrent.MagnetLink == "" {
+			continue
+		}
+		feed.Channel.Items = append(feed.Channel.Items, buildTorznabItem(torrent))
+	}
+
+	return feed
+}
+
+func buildTorznabItem(torrent *parser.TorrentFile) torznabRSSItem {
+	size := int64(torrent.Size * torznabBytesPerGigabyte)
+	category := torznabCategoryID(torrent.Category)
+
+	item := torznabRSSItem{
+		Title:    torrent.Name,
+		GUID:     torrent.MagnetLink,
+		Link:     torrent.MagnetLink,
+		Size:     size,
+		Category: category,
+	}
+	if strings.HasPrefix(torrent.Href, "http") {
+		item.Comments = torrent.Href
+	}
+
+	// a magnet, not /download: its links only live in memory and a grab can come much later
+	item.Enclosure.URL = torrent.MagnetLink
+	item.Enclosure.Length = size
+	item.Enclosure.Type = "x-scheme-handler/magnet"
+
+	item.Attrs = []torznabRSSAttr{
+		{Name: "category", Value: strconv.Itoa(category)},
+		{Name: "size", Value: strconv.FormatInt(size, 10)},
+		{Name: "seeders", Value: strconv.Itoa(torrent.Seeders)},
+		{Name: "peers", Value: strconv.Itoa(torrent.Seeders + torrent.Leechers)},
+		{Name: "grabs", Value: strconv.Itoa(torrent.Downloads)},
+		{Name: "magneturl", Value: torrent.MagnetLink},
+		{Name: "krakeneyescore", Value: strconv.FormatFloat(torrent.Score, 'f', 2, 64)},
+	}
+	if torrent.InfoHash != "" {
+		item.Attrs = append(item.Attrs, torznabRSSAttr{Name: "infohash", Value: torrent.InfoHash})
+	}
+
+	return item
+}
+
+// torznabCategoryID maps a site's category label to a top level newznab id, Sonarr
+// expects anime in its own TV sub category
+func torznabCategoryID(category string) int {
+	if strings.Contains(strings.ToLower(category), "anime") {
+		return torznabAnimeCategory
+	}
+
+	switch parser.NormalizeCategory(category) {
+	case parser.CategoryGames:
+		return 1000
+	case parser.CategoryMovies:
+		return 2000
+	case parser.CategoryMusic:
+		return 3000
+	case parser.CategoryTV:
+		return 5000
+	}
+
+	for _, torznabCategory := range torznabCategories {
+		if strings.EqualFold(torznabCategory.Name, strings.TrimSpace(category)) {
+			return torznabCategory.ID
+		}
+	}
+	return torznabDefaultCategory
+}
+
+func writeTorznabXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		log.Printf("⚠️ Warning: failed to write torznab reply: %v", err)
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("⚠️ Warning: failed to encode torznab reply: %v", err)
+	}
+}
+
+func writeTorznabError(w http.ResponseWriter, code int, description string) {
+	writeTorznabXML(w, torznabErrorReply{Code: code, Description: description})
+}
@@ -0,0 +1,332 @@
+package webui
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sanjaix21/krakeneye/internal/magnet"
+	"sanjaix21/krakeneye/internal/parser"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubParser returns canned results and remembers the last search it got
+type stubParser struct {
+	torrents []parser.TorrentFile
+	err      error
+
+	query string
+	opts  parser.SearchOptions
+}
+
+func (s *stubParser) Search(ctx context.Context, query string, opts parser.SearchOptions) ([]parser.TorrentFile, error) {
+	s.query, s.opts = query, opts
+	if s.err != nil {
+		return nil, s.err
+	}
+	return slices.Clone(s.torrents), nil
+}
+
+func (s *stubParser) EnrichTorrents(ctx context.Context, torrents []parser.TorrentFile, opts parser.EnrichOptions) []parser.TorrentFile {
+	return torrents
+}
+
+// feed is what a torznab client reads of a reply, attrs live in the torznab namespace
+type feed struct {
+	XMLName xml.Name `xml:"rss"`
+	Items   []struct {
+		Title     string `xml:"title"`
+		GUID      string `xml:"guid"`
+		Link      string `xml:"link"`
+		Comments  string `xml:"comments"`
+		Size      int64  `xml:"size"`
+		Category  int    `xml:"category"`
+		Enclosure struct {
+			URL    string `xml:"url,attr"`
+			Length int64  `xml:"length,attr"`
+			Type   string `xml:"type,attr"`
+		} `xml:"enclosure"`
+		Attrs []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"http://torznab.com/schemas/2015/feed attr"`
+	} `xml:"channel>item"`
+}
+
+func (f feed) attr(item int, name string) string {
+	for _, attr := range f.Items[item].Attrs {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func testMagnet(i int) string {
+	return fmt.Sprintf("magnet:?xt=urn:btih:%040x", i+1)
+}
+
+func testTorrents(n int) []parser.TorrentFile {
+	var torrents []parser.TorrentFile
+	for i := range n {
+		torrents = append(torrents, parser.TorrentFile{
+			Name:       fmt.Sprintf("The.Matrix.1999.720p.WEB.x264-GRP%d", i),
+			Href:       fmt.Sprintf("https://1337x.invalid/torrent/%d/", i),
+			MagnetLink: testMagnet(i),
+			InfoHash:   fmt.Sprintf("%040x", i+1),
+			Size:       1.5,
+			Seeders:    10 + i,
+			Leechers:   3,
+			Category:   "Movies",
+		})
+	}
+	return torrents
+}
+
+func torznabGet(t *testing.T, handler http.Handler, params string) string {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/torznab?" + params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/xml") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func decodeFeed(t *testing.T, body string) feed {
+	t.Helper()
+
+	var f feed
+	if err := xml.Unmarshal([]byte(body), &f); err != nil {
+		t.Fatalf("reply is no feed: %v\n%s", err, body)
+	}
+	return f
+}
+
+func errorCode(t *testing.T, body string) int {
+	t.Helper()
+
+	var reply torznabErrorReply
+	if err := xml.Unmarshal([]byte(body), &reply); err != nil {
+		t.Fatalf("reply is no error: %v\n%s", err, body)
+	}
+	return reply.Code
+}
+
+func TestTorznabCaps(t *testing.T) {
+	body := torznabGet(t, torznabHandler(&stubParser{}, magnet.TrackerList{}), "t=caps")
+
+	var caps torznabCaps
+	if err := xml.Unmarshal([]byte(body), &caps); err != nil {
+		t.Fatal(err)
+	}
+	if caps.Server.Title != "KrakenEye" || caps.Limits.Max != 100 {
+		t.Errorf("server %q limit %d", caps.Server.Title, caps.Limits.Max)
+	}
+	if caps.Searching.Search.Available != "yes" || caps.Searching.TVSearch.SupportedParams != "q,season,ep" {
+		t.Errorf("searching = %+v", caps.Searching)
+	}
+	if params := caps.Searching.MovieSearch.SupportedParams; params != "q" {
+		t.Errorf("movie-search params = %q, the sites can't search imdb ids", params)
+	}
+	if !strings.Contains(body, `<category id="5000" name="TV"><subcat id="5070" name="Anime"></subcat></category>`) {
+		t.Errorf("no TV/Anime category in\n%s", body)
+	}
+}
+
+func TestTorznabSearch(t *testing.T) {
+	torrents := testTorrents(3)
+	torrents[1].Category = "TV Shows"
+	torrents[2].MagnetLink = "" // nothing to grab, left out
+	stub := &stubParser{torrents: torrents}
+
+	body := torznabGet(t, torznabHandler(stub, magnet.TrackerList{}), "t=search&q=the+matrix&cat=2000,2040")
+	if stub.query != "the matrix" || stub.opts.Category != parser.CategoryMovies || stub.opts.MaxResults != 100 {
+		t.Errorf("searched %q with %+v", stub.query, stub.opts)
+	}
+
+	if !strings.HasPrefix(body, xml.Header) || !strings.Contains(body, `xmlns:torznab="http://torznab.com/schemas/2015/feed"`) {
+		t.Errorf("no xml header or torznab namespace in\n%s", body)
+	}
+
+	f := decodeFeed(t, body)
+	if len(f.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(f.Items))
+	}
+
+	// seeders rank the otherwise equal torrents
+	item := f.Items[0]
+	if item.Title != torrents[1].Name || item.Category != 5000 || f.attr(0, "category") != "5000" {
+		t.Errorf("first item %q category %d, want %q in TV", item.Title, item.Category, torrents[1].Name)
+	}
+	if item.GUID != testMagnet(1) || item.Link != testMagnet(1) || item.Comments != "https://1337x.invalid/torrent/1/" {
+		t.Errorf("guid %q link %q comments %q", item.GUID, item.Link, item.Comments)
+	}
+	if item.Enclosure.URL != testMagnet(1) || item.Enclosure.Type != "x-scheme-handler/magnet" || item.Enclosure.Length != item.Size {
+		t.Errorf("enclosure = %+v", item.Enclosure)
+	}
+	if item.Size != 1610612736 || f.attr(0, "size") != "1610612736" {
+		t.Errorf("size %d attr %q, want 1.5 GiB in bytes", item.Size, f.attr(0, "size"))
+	}
+	if f.attr(0, "seeders") != "11" || f.attr(0, "peers") != "14" || f.attr(0, "magneturl") != testMagnet(1) || f.attr(0, "infohash") != torrents[1].InfoHash {
+		t.Errorf("attrs = %+v", item.Attrs)
+	}
+	if _, err := strconv.ParseFloat(f.attr(0, "krakeneyescore"), 64); err != nil {
+		t.Errorf("krakeneyescore = %q", f.attr(0, "krakeneyescore"))
+	}
+	if f.Items[1].Category != 2000 {
+		t.Errorf("Movies labelled torrent in category %d", f.Items[1].Category)
+	}
+}
+
+func TestTorznabQueries(t *testing.T) {
+	year := strconv.Itoa(time.Now().Year())
+
+	tests := []struct {
+		params   string
+		query    string
+		category string
+	}{
+		{"t=tvsearch&q=the+bear&season=2&ep=3", "the bear S02E03", parser.CategoryTV},
+		{"t=tvsearch&q=the+bear&season=2", "the bear S02", parser.CategoryTV},
+		{"t=tvsearch&q=the+bear&ep=3", "the bear", parser.CategoryTV},
+		{"t=movie&q=dune+2021&imdbid=tt1160419", "dune 2021", parser.CategoryMovies},
+		{"t=search&q=ubuntu&cat=1000", "ubuntu", parser.CategoryGames},
+		{"t=search&q=ubuntu&cat=100001,3000", "ubuntu", parser.CategoryMusic},
+		{"t=search&q=ubuntu", "ubuntu", parser.CategoryAll},
+
+		// the connection test and rss sync of the *arr clients
+		{"t=search", year, parser.CategoryAll},
+		{"t=tvsearch&cat=5000,5070", year, parser.CategoryTV},
+		{"t=movie", year, parser.CategoryMovies},
+	}
+
+	for _, tt := range tests {
+		stub := &stubParser{torrents: testTorrents(1)}
+		body := torznabGet(t, torznabHandler(stub, magnet.TrackerList{}), tt.params)
+
+		if stub.query != tt.query || stub.opts.Category != tt.category {
+			t.Errorf("%s searched %q in %q, want %q in %q", tt.params, stub.query, stub.opts.Category, tt.query, tt.category)
+		}
+		if f := decodeFeed(t, body); len(f.Items) != 1 {
+			t.Errorf("%s gave %d items, want the result", tt.params, len(f.Items))
+		}
+	}
+}
+
+func TestTorznabImdbIDOnly(t *testing.T) {
+	stub := &stubParser{torrents: testTorrents(1)}
+	body := torznabGet(t, torznabHandler(stub, magnet.TrackerList{}), "t=movie&imdbid=tt1160419")
+
+	if stub.query != "" {
+		t.Errorf("searched %q for a bare imdb id", stub.query)
+	}
+	if f := decodeFeed(t, body); len(f.Items) != 0 {
+		t.Errorf("got %d items, want an empty feed", len(f.Items))
+	}
+}
+
+func TestTorznabLimit(t *testing.T) {
+	stub := &stubParser{torrents: testTorrents(5)}
+	handler := torznabHandler(stub, magnet.TrackerList{})
+
+	f := decodeFeed(t, torznabGet(t, handler, "t=search&q=matrix&limit=2"))
+	if stub.opts.MaxResults != 2 || len(f.Items) != 2 {
+		t.Errorf("limit=2 searched for %d and gave %d items", stub.opts.MaxResults, len(f.Items))
+	}
+
+	for _, limit := range []string{"500", "0", "-1", "abc"} {
+		torznabGet(t, handler, "t=search&q=matrix&limit="+limit)
+		if stub.opts.MaxResults != 100 {
+			t.Errorf("limit=%s searched for %d results, want the cap of 100", limit, stub.opts.MaxResults)
+		}
+	}
+}
+
+func TestTorznabErrors(t *testing.T) {
+	handler := torznabHandler(&stubParser{}, magnet.TrackerList{})
+
+	if code := errorCode(t, torznabGet(t, handler, "q=matrix")); code != torznabErrMissingParam {
+		t.Errorf("missing t = code %d, want %d", code, torznabErrMissingParam)
+	}
+	if code := errorCode(t, torznabGet(t, handler, "t=music&q=matrix")); code != torznabErrNoSuchFunction {
+		t.Errorf("unknown t = code %d, want %d", code, torznabErrNoSuchFunction)
+	}
+
+	failing := torznabHandler(&stubParser{err: parser.ErrBlocked}, magnet.TrackerList{})
+	if code := errorCode(t, torznabGet(t, failing, "t=search&q=matrix")); code != torznabErrUnknown {
+		t.Errorf("failed search = code %d, want %d", code, torznabErrUnknown)
+	}
+
+	// nothing found is an empty feed, not an error
+	empty := torznabHandler(&stubParser{err: parser.ErrNoResults}, magnet.TrackerList{})
+	if f := decodeFeed(t, torznabGet(t, empty, "t=search&q=matrix")); len(f.Items) != 0 {
+		t.Errorf("got %d items without results", len(f.Items))
+	}
+}
+
+func TestTorznabAPIKey(t *testing.T) {
+	t.Setenv("KRAKENEYE_APIKEY", "s3cret")
+	stub := &stubParser{torrents: testTorrents(1)}
+	handler := torznabHandler(stub, magnet.TrackerList{})
+
+	for _, params := range []string{"t=caps", "t=search&q=matrix&apikey=wrong", "t=search&q=matrix&apikey="} {
+		if code := errorCode(t, torznabGet(t, handler, params)); code != torznabErrBadAPIKey {
+			t.Errorf("%s = code %d, want %d", params, code, torznabErrBadAPIKey)
+		}
+	}
+	if stub.query != "" {
+		t.Errorf("searched %q without the api key", stub.query)
+	}
+
+	f := decodeFeed(t, torznabGet(t, handler, "t=search&q=matrix&apikey="+url.QueryEscape("s3cret")))
+	if len(f.Items) != 1 {
+		t.Errorf("got %d items with the api key, want 1", len(f.Items))
+	}
+}
+
+func TestTorznabCategoryID(t *testing.T) {
+	tests := []struct {
+		label string
+		want  int
+	}{
+		{"Movies", 2000},
+		{"Movies/HD", 2000},
+		{"movie", 2000},
+		{"TV", 5000},
+		{"TV Shows", 5000},
+		{"TV/HD", 5000},
+		{"Anime", 5070},
+		{"Games", 1000},
+		{"Music", 3000},
+		{"Apps", 4000},
+		{"XXX", 6000},
+		{"Books", 7000},
+		{"Other", 8000},
+		{"", 8000},
+	}
+	for _, tt := range tests {
+		if got := torznabCategoryID(tt.label); got != tt.want {
+			t.Errorf("torznabCategoryID(%q) = %d, want %d", tt.label, got, tt.want)
+		}
+	}
+}
@@ -1,51 +1,176 @@
 package webui
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"sanjaix21/krakeneye/internal/magnet"
 	"sanjaix21/krakeneye/internal/parser"
 	"sanjaix21/krakeneye/internal/ranker"
+	"sanjaix21/krakeneye/internal/release"
 	"sanjaix21/krakeneye/internal/sites"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func StartServer(port int) {
+// StartServer serves the web UI, the JSON API and the torznab endpoint. Mirrors come from
+// registry, every magnet link handed out gets the trackers of the list added.
+func StartServer(port int, trackers magnet.TrackerList, registry *sites.MirrorRegistry) {
 	fmt.Printf("🕸️  Launching KrakenEye WebUI on http://localhost:%d\n", port)
 
-	result, err := sites.FindFirstWorkingMirror()
+	mirrors, err := registry.FindWorkingMirrors(sites.AllSites())
 	if err != nil {
 		log.Fatalf("No working mirror found. Error: %v", err)
 	}
+	for _, result := range mirrors {
+		log.Printf("🔸 Searching %s on %s (%s)", result.SiteName, result.Mirror, result.Latency.Round(time.Millisecond))
+	}
 
-	torrentParser, err := parser.NewParser(result.SiteName, result.Mirror)
+	torrentParser, err := sites.NewAggregator(mirrors, registry)
 	if err != nil {
-		log.Fatalf("Could not create parser: %v", err)
+		log.Fatalf("%v", err)
 	}
 
+	links := newDownloadLinks()
+
 	// Serve static HTML + JS
 	http.Handle("/", http.FileServer(http.Dir("internal/webui/static")))
 
 	http.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
 		query := r.URL.Query().Get("q")
-		torrents, err := torrentParser.Search(query)
+		opts, err := searchOptionsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		preferences, err := preferencesFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// r.Context() ends when the browser gives up on the request, scraping stops with it
+		enrichedPtrs, err := searchAndRank(r.Context(), torrentParser, query, opts, parser.DefaultEnrichOptions(), preferences, trackers)
+		if r.Context().Err() != nil {
+			log.Printf("🔌 Client left, dropped search for %q", query)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Search failed", http.StatusInternalServerError)
+			status := searchErrorStatus(err)
+			if status >= http.StatusInternalServerError {
+				log.Printf("⚠️ search for %q failed: %v", query, err)
+			}
+			http.Error(w, err.Error(), status)
 			return
 		}
 
-		enriched := torrentParser.EnrichTorrents(torrents)
-		rankerFunc := &ranker.RankTorrent{}
-		var enrichedPtrs []*parser.TorrentFile
-		for i := range enriched {
-			enriched[i].Score = rankerFunc.RankTorrentFile(enriched[i])
-			enriched[i].SiteName = result.SiteName
-			enrichedPtrs = append(enrichedPtrs, &enriched[i])
+		for _, torrent := range enrichedPtrs {
+			links.remember(*torrent)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(enrichedPtrs)
 	})
 
+	// .torrent files of search results, fetched and checked by the backend
+	http.HandleFunc("/download", downloadHandler(links))
+
+	// Sonarr/Radarr indexer endpoint
+	http.HandleFunc("/api/torznab", torznabHandler(torrentParser, trackers))
+
+	// layout drift diagnosis for monitoring
+	http.HandleFunc("/api/health/parsers", healthHandler(torrentParser))
+
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 }
+
+// searchAndRank runs a search, enriches every result, scores it and adds the trackers to its magnet
+func searchAndRank(
+	ctx context.Context,
+	torrentParser parser.TorrentParser,
+	query string,
+	opts parser.SearchOptions,
+	enrichOpts parser.EnrichOptions,
+	preferences ranker.Preferences,
+	trackers magnet.TrackerList,
+) ([]*parser.TorrentFile, error) {
+	torrents, err := torrentParser.Search(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	enriched := torrentParser.EnrichTorrents(ctx, torrents, enrichOpts)
+	rankerFunc := &ranker.RankTorrent{Preferences: preferences}
+	var enrichedPtrs []*parser.TorrentFile
+	for i := range enriched {
+		if rankerFunc.Hidden(enriched[i]) {
+			continue
+		}
+		enriched[i].Score = rankerFunc.RankTorrentFile(enriched[i])
+		enriched[i].MagnetLink = trackers.Augment(enriched[i].MagnetLink)
+		enrichedPtrs = append(enrichedPtrs, &enriched[i])
+	}
+
+	return enrichedPtrs, nil
+}
+
+// searchErrorStatus maps a search error to the HTTP status the API answers with
+func searchErrorStatus(err error) int {
+	var statusErr *parser.StatusError
+	switch {
+	case errors.Is(err, parser.ErrNoResults):
+		return http.StatusNotFound
+	case errors.Is(err, parser.ErrBlocked):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, parser.ErrMirrorUnreachable), errors.Is(err, parser.ErrLayoutChanged), errors.Is(err, parser.ErrNotSite), errors.As(err, &statusErr):
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// preferencesFromQuery reads the ranking preferences ?hdr=hdr10&surround=1&lang=hindi&safe=1
+func preferencesFromQuery(params url.Values) (ranker.Preferences, error) {
+	hdr, err := ranker.ParseHDRPreference(params.Get("hdr"))
+	if err != nil {
+		return ranker.Preferences{}, err
+	}
+
+	surround, _ := strconv.ParseBool(params.Get("surround"))
+	hideSuspicious, _ := strconv.ParseBool(params.Get("safe"))
+	return ranker.Preferences{
+		HDR:      hdr,
+		Surround: surround,
+		Language: release.NormalizeLanguage(strings.TrimSpace(params.Get("lang"))),
+
+		HideSuspicious: hideSuspicious,
+	}, nil
+}
+
+// searchOptionsFromQuery reads ?pages=N&limit=N&cat=movies, missing or bad numbers keep
+// the defaults, an unknown category is an error
+func searchOptionsFromQuery(params url.Values) (parser.SearchOptions, error) {
+	opts := parser.DefaultSearchOptions()
+
+	category, err := parser.ParseCategory(params.Get("cat"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Category = category
+
+	if pages, err := strconv.Atoi(params.Get("pages")); err == nil && pages > 0 {
+		opts.MaxPages = pages
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+		opts.MaxResults = limit
+	}
+
+	return opts, nil
+}
@@ -5,17 +5,24 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"sanjaix21/krakeneye/internal/display"
+	"sanjaix21/krakeneye/internal/httpclient"
+	"sanjaix21/krakeneye/internal/magnet"
 	"sanjaix21/krakeneye/internal/parser"
 	"sanjaix21/krakeneye/internal/ranker"
+	"sanjaix21/krakeneye/internal/release"
 	"sanjaix21/krakeneye/internal/sites"
 	"sanjaix21/krakeneye/internal/webui"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func getUserInput(query string) string {
@@ -39,22 +46,111 @@ func getUserInput(query string) string {
 	}
 }
 
-func searchMedia(torrentParser parser.TorrentParser) ([]parser.TorrentFile, error) {
-	tempQuery := getUserInput("search")
+// mirrorConnection is the parser of every site the CLI talks to. A site whose mirror dies
+// moves on to its next healthy mirror by itself.
+type mirrorConnection struct {
+	registry *sites.MirrorRegistry
+	sites    []sites.Site
+	parser   parser.TorrentParser
+}
+
+func (c *mirrorConnection) connect() error {
+	results, err := c.registry.FindWorkingMirrors(c.sites)
+	if err != nil {
+		return err
+	}
 
-	torrents, err := torrentParser.Search(tempQuery)
+	aggregator, err := sites.NewAggregator(results, c.registry)
 	if err != nil {
-		if err.Error() == "none" {
-			fmt.Printf("No torrents found. Try checking name of the movie/tv\n")
+		return err
+	}
+
+	fmt.Printf("✅ Working Mirrors Found: %d of %d sites\n", len(results), len(c.sites))
+	for _, result := range results {
+		cached := ""
+		if result.Cached {
+			cached = ", remembered"
 		}
-		log.Fatal(err)
+		fmt.Printf("🔸 %-8s: %s (%s%s)\n", result.SiteName, result.Mirror, result.Latency.Round(time.Millisecond), cached)
 	}
 
-	return torrents, nil
+	c.parser = aggregator
+	return nil
 }
 
 func main() {
-	if len(os.Args) > 1 && os.Args[1] == "--web" {
+	webMode := flag.Bool("web", false, "launch the web UI instead of the interactive CLI")
+	siteFlag := flag.String("site", "", "only search this site instead of every site with a working mirror")
+	refreshMirrors := flag.Bool("refresh-mirrors", false, "probe mirrors even when the cached mirror health is recent")
+	maxPages := flag.Int("pages", 1, "result pages to scrape per search")
+	maxResults := flag.Int("max-results", 0, "stop after this many results per search (0 = no limit)")
+	categoryFlag := flag.String("category", "", "only search one category: movies, tv, games or music")
+	workers := flag.Int("workers", 5, "detail pages fetched at the same time")
+	timeout := flag.Duration("timeout", 15*time.Second, "timeout for each detail page")
+	rate := flag.Float64("rate", 2, "max requests per second to a single mirror (0 = unlimited)")
+	retries := flag.Int("retries", 2, "retries for a request that hits a network error, 5xx or 429")
+	hdrFlag := flag.String("hdr", "any", "HDR preference for ranking: any, hdr10 (buries Dolby Vision only), dv or sdr")
+	surround := flag.Bool("surround", false, "rank 5.1/7.1 audio above stereo")
+	audioLanguage := flag.String("audio-lang", "", "audio language a release has to carry, e.g. hindi")
+	hideSuspicious := flag.Bool("hide-suspicious", false, "drop torrents with executables, archives or sample-only payloads instead of ranking them last")
+	trackersFile := flag.String("trackers", "", "file with one tracker announce URL per line, added to every magnet link")
+	maxTrackers := flag.Int("max-trackers", magnet.DefaultMaxTrackers, "most trackers a magnet link ends up with when --trackers is set")
+	searchTimeout := flag.Duration("search-timeout", 2*time.Minute, "give up on a whole search (pages and details) after this long")
+	flag.Parse()
+
+	httpOptions := httpclient.DefaultOptions()
+	httpOptions.RequestsPerSecond = *rate
+	httpOptions.MaxRetries = *retries
+	httpclient.Configure(httpOptions)
+
+	switch flag.Arg(0) {
+	case "doctor":
+		os.Exit(runDoctor(flag.Args()[1:]))
+	case "inspect":
+		os.Exit(runInspect(flag.Args()[1:]))
+	case "mirrors":
+		os.Exit(runMirrors(flag.Args()[1:]))
+	}
+
+	category, err := parser.ParseCategory(*categoryFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	hdr, err := ranker.ParseHDRPreference(*hdrFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	preferences := ranker.Preferences{
+		HDR:      hdr,
+		Surround: *surround,
+		Language: release.NormalizeLanguage(strings.TrimSpace(*audioLanguage)),
+
+		HideSuspicious: *hideSuspicious,
+	}
+
+	var trackers magnet.TrackerList
+	if *trackersFile != "" {
+		trackers, err = magnet.LoadTrackerList(*trackersFile, *maxTrackers)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("📡 Loaded %d trackers from %s\n", len(trackers.Trackers), *trackersFile)
+	}
+
+	searchOptions := parser.SearchOptions{
+		MaxPages:   *maxPages,
+		MaxResults: *maxResults,
+		Category:   category,
+	}
+
+	enrichOptions := parser.EnrichOptions{
+		Concurrency: *workers,
+		Timeout:     *timeout,
+		Progress:    display.PrintProgress,
+	}
+
+	if *webMode {
 		port := 8787
 
 		for {
@@ -65,44 +161,80 @@ func main() {
 			}
 
 			_ = ln.Close()
-			webui.StartServer(port)
+			registry := sites.OpenDefaultMirrorRegistry()
+			if *refreshMirrors {
+				registry.TTL = 0
+			}
+			webui.StartServer(port, trackers, registry)
 			return
 		}
 	}
 
-	fmt.Println("🏴‍☠️ Scanning for a working piracy site mirror...")
-
-	result, err := sites.FindFirstWorkingMirror()
-	if err != nil {
-		log.Fatalf("❌ No working mirror found. Error: %v", err)
+	conn := &mirrorConnection{
+		registry: sites.OpenDefaultMirrorRegistry(),
+		sites:    sites.AllSites(),
+	}
+	if *refreshMirrors {
+		conn.registry.TTL = 0
+	}
+	if *siteFlag != "" {
+		site, ok := sites.FindSite(*siteFlag)
+		if !ok {
+			log.Fatalf("❌ Unknown site %q", *siteFlag)
+		}
+		conn.sites = []sites.Site{site}
 	}
 
-	fmt.Println("✅ Working Mirror Found!")
-	fmt.Printf("🔸 Site    : %s\n", result.SiteName)
-	fmt.Printf("🔗 Mirror  : %s\n", result.Mirror)
+	fmt.Println("🏴‍☠️ Scanning for working piracy site mirrors...")
 
-	torrentParser, err := parser.NewParser(result.SiteName, result.Mirror)
-	if err != nil {
-		log.Fatalf("Could not create parser: %v", err)
+	if err := conn.connect(); err != nil {
+		log.Fatalf("❌ No working mirror found. Error: %v", err)
 	}
 
 	for {
+		query := getUserInput("search")
+
+		// a hung mirror can't block the CLI for longer than this
+		ctx, cancel := context.WithTimeout(context.Background(), *searchTimeout)
 
-		torrents, err := searchMedia(torrentParser)
+		torrents, err := conn.parser.Search(ctx, query, searchOptions)
 		if err != nil {
-			log.Fatalf("Failed to search for media")
+			cancel()
+
+			switch {
+			case errors.Is(err, parser.ErrNoResults):
+				fmt.Printf("🤷 No torrents found. Try checking the name of the movie/tv or adding the year\n")
+				continue
+			case errors.Is(err, context.DeadlineExceeded):
+				fmt.Printf("⌛ Search took longer than %s, try again or raise --search-timeout\n", *searchTimeout)
+				continue
+			case parser.IsMirrorError(err):
+				fmt.Printf("🌊 Every mirror of every site failed, try again later: %v\n", err)
+				continue
+			default:
+				log.Fatalf("❌ Failed to search for media: %v", err)
+			}
 		}
 
-		enrichedTorrents := torrentParser.EnrichTorrents(torrents)
-		rankerFunc := &ranker.RankTorrent{}
+		enrichedTorrents := conn.parser.EnrichTorrents(ctx, torrents, enrichOptions)
+		cancel()
+		rankerFunc := &ranker.RankTorrent{Preferences: preferences}
 
 		var torrentPointers []*parser.TorrentFile
 		for i := range enrichedTorrents {
+			if rankerFunc.Hidden(enrichedTorrents[i]) {
+				continue
+			}
 			enrichedTorrents[i].Score = rankerFunc.RankTorrentFile(enrichedTorrents[i])
-			enrichedTorrents[i].SiteName = result.SiteName
+			enrichedTorrents[i].MagnetLink = trackers.Augment(enrichedTorrents[i].MagnetLink)
 			torrentPointers = append(torrentPointers, &enrichedTorrents[i])
 		}
 
+		if len(torrentPointers) == 0 {
+			fmt.Println("🛡️ Every result was flagged as suspicious, search again without --hide-suspicious to see them")
+			continue
+		}
+
 		displayOutput := display.NewDisplayManager(torrentPointers)
 		displayOutput.ListTorrents()
 		option, err := strconv.Atoi(getUserInput("option"))
@@ -114,6 +246,12 @@ func main() {
 		fmt.Println("⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘")
 		fmt.Println(torrentPointers[option].MagnetLink)
 		fmt.Println("⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘⫘")
+		if mirror := torrentPointers[option].Mirror; mirror != "" {
+			fmt.Printf("🛰️ Found on %s via %s\n", torrentPointers[option].SiteName, mirror)
+		}
+		if torrentURL := torrentPointers[option].TorrentURL; torrentURL != "" {
+			fmt.Printf("📥 .torrent: %s (see it with: krakeneye inspect <url>)\n", torrentURL)
+		}
 
 		newSearch := getUserInput("new")
 		if newSearch != "y" {
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sanjaix21/krakeneye/internal/display"
+	"sanjaix21/krakeneye/internal/sites"
+)
+
+// runMirrors probes every mirror of every site, prints how each one answered and stores it
+// in the mirror registry. It returns the exit code, 1 when no site has a working mirror.
+func runMirrors(args []string) int {
+	mirrorFlags := flag.NewFlagSet("mirrors", flag.ExitOnError)
+	siteName := mirrorFlags.String("site", "", "only probe the mirrors of this site")
+	cached := mirrorFlags.Bool("cached", false, "reuse recent results and skip mirrors whose circuit breaker is open, like a search does")
+	mirrorFlags.Parse(args)
+
+	siteList := sites.AllSites()
+	if *siteName != "" {
+		site, ok := sites.FindSite(*siteName)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown site %q\n", *siteName)
+			return 2
+		}
+		siteList = []sites.Site{site}
+	}
+
+	fmt.Printf("📡 Probing %d sites...\n", len(siteList))
+	registry := sites.OpenDefaultMirrorRegistry()
+	var results []sites.MirrorResult
+	if *cached {
+		results = registry.Probe(siteList)
+	} else {
+		results = registry.Refresh(siteList)
+	}
+	fastest := sites.FastestMirrors(results)
+	display.PrintMirrorTable(results, fastest)
+
+	if len(fastest) == 0 {
+		fmt.Println("💀 No working mirror found for any site")
+		return 1
+	}
+	return 0
+}